@@ -0,0 +1,307 @@
+// Command go-env-doc generates reference documentation of the environment variables read by
+// the structs of a Go package, taken from their env and desc tags and doc comments. It reads
+// the source, so binaries don't have to be built and run to document them.
+//
+// Usage:
+//
+//	go-env-doc [-format markdown|html] [-o file] [-type Config,...] [dir]
+//
+// Keep the docs of a package in sync with go generate:
+//
+//	//go:generate go run github.com/stenhagglund/go-env/cmd/go-env-doc -o ENV.md
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	env "github.com/stenhagglund/go-env"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("go-env-doc: ")
+
+	format := flag.String("format", "markdown", "output format, markdown or html")
+	output := flag.String("o", "", "output file, defaults to stdout")
+	typeNames := flag.String("type", "", "comma separated names of the struct types to document, defaults to all")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 1 {
+		log.Fatal("expected at most one package directory")
+	} else if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	}
+	structs, err := load(dir, names)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	switch *format {
+	case "markdown":
+		err = writeMarkdown(&buf, structs)
+	case "html":
+		err = writeHTML(&buf, structs)
+	default:
+		err = fmt.Errorf("invalid format %q, valid options are: \"markdown\", \"html\"", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*output, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// structDoc documents the variables read by a struct type, including its nested structs
+type structDoc struct {
+	Name      string
+	Doc       string
+	Variables []variableDoc
+}
+
+// variableDoc documents a single variable
+type variableDoc struct {
+	Name        string
+	Type        string
+	Default     string
+	Required    string
+	Description string
+}
+
+// loader finds the env tags of the structs of a type checked package
+type loader struct {
+	fset   *token.FileSet
+	pkg    *types.Package
+	fields map[*types.Var]*ast.Field // declarations of the fields of the package
+}
+
+// load documents the struct types of the package in dir which read environment variables, in
+// declaration order, or those listed in names
+func load(dir string, names []string) ([]structDoc, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{fset: token.NewFileSet(), fields: map[*types.Var]*ast.Field{}}
+	var files []*ast.File
+	for _, name := range buildPkg.GoFiles {
+		file, err := parser.ParseFile(l.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	// types of fields which can't be checked are reported as written in the source
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	conf := types.Config{Importer: importer.ForCompiler(l.fset, "source", nil), Error: func(error) {}}
+	l.pkg, _ = conf.Check(buildPkg.ImportPath, l.fset, files, info)
+
+	typeDocs := map[string]string{}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GenDecl:
+				for _, spec := range n.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok {
+						doc := spec.Doc
+						if doc == nil && len(n.Specs) == 1 {
+							doc = n.Doc
+						}
+						typeDocs[spec.Name.Name] = doc.Text()
+					}
+				}
+			case *ast.Field:
+				for _, name := range n.Names {
+					if v, ok := info.Defs[name].(*types.Var); ok {
+						l.fields[v] = n
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	scope := l.pkg.Scope()
+	all := len(names) == 0
+	if all {
+		names = scope.Names()
+		sort.Slice(names, func(i, j int) bool {
+			return scope.Lookup(names[i]).Pos() < scope.Lookup(names[j]).Pos()
+		})
+	}
+
+	var docs []structDoc
+	for _, name := range names {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			if all {
+				continue
+			}
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		s, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+
+		doc := structDoc{Name: name, Doc: strings.TrimSpace(typeDocs[name])}
+		if err := l.collect(s, "", &doc.Variables); err != nil {
+			return nil, err
+		}
+		if len(doc.Variables) > 0 {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// collect appends the variables read by the fields of s to vars, recursing into untagged
+// struct fields like env.Parse does
+func (l *loader) collect(s *types.Struct, path string, vars *[]variableDoc) error {
+	for i := 0; i < s.NumFields(); i++ {
+		field := s.Field(i)
+		tagValue := reflect.StructTag(s.Tag(i)).Get("env")
+		if tagValue == "" {
+			if nested, ok := field.Type().Underlying().(*types.Struct); ok {
+				if err := l.collect(nested, path+field.Name()+".", vars); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		tag, err := env.ParseTag(tagValue)
+		if err != nil {
+			return fmt.Errorf("%s: %s%s: %w", l.fset.Position(field.Pos()), path, field.Name(), err)
+		}
+		desc := reflect.StructTag(s.Tag(i)).Get("desc")
+		if desc == "" {
+			desc = l.comment(field)
+		}
+		doc := variableDoc{
+			Name:        tag.Name,
+			Type:        l.typeString(field),
+			Required:    tag.Requirement(),
+			Description: desc,
+		}
+		if tag.DefaultApplies() {
+			doc.Default = tag.Default
+		}
+		*vars = append(*vars, doc)
+	}
+	return nil
+}
+
+// comment returns the doc or line comment of a field on a single line
+func (l *loader) comment(field *types.Var) string {
+	decl := l.fields[field]
+	if decl == nil {
+		return ""
+	}
+	text := decl.Doc.Text()
+	if text == "" {
+		text = decl.Comment.Text()
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// typeString returns the type of field as written in the source if declared in the package,
+// qualified by package name otherwise
+func (l *loader) typeString(field *types.Var) string {
+	if decl := l.fields[field]; decl != nil {
+		return types.ExprString(decl.Type)
+	}
+	return types.TypeString(field.Type(), func(pkg *types.Package) string {
+		if pkg == l.pkg {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+// writeMarkdown writes a table of variables for each struct
+func writeMarkdown(w io.Writer, structs []structDoc) error {
+	var b strings.Builder
+	b.WriteString("# Environment variables\n")
+	for _, s := range structs {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.Name)
+		if s.Doc != "" {
+			fmt.Fprintf(&b, "%s\n\n", s.Doc)
+		}
+		b.WriteString("| Variable | Type | Default | Required | Description |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, v := range s.Variables {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				markdownCode(v.Name), markdownCode(v.Type), markdownCode(v.Default), markdownCell(v.Required), markdownCell(v.Description))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes the characters of value which would end a table cell or row
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+// markdownCode formats a non-empty value as inline code, using a longer run of backticks than
+// the value contains
+func markdownCode(value string) string {
+	if value == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(value, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(value, "`") || strings.HasSuffix(value, "`") {
+		value = " " + value + " "
+	}
+	return fence + markdownCell(value) + fence
+}
+
+var htmlTemplate = template.Must(template.New("doc").Parse(`<h1>Environment variables</h1>
+{{range .}}
+<h2>{{.Name}}</h2>
+{{if .Doc}}<p>{{.Doc}}</p>
+{{end}}<table>
+<tr><th>Variable</th><th>Type</th><th>Default</th><th>Required</th><th>Description</th></tr>
+{{range .Variables}}<tr><td><code>{{.Name}}</code></td><td><code>{{.Type}}</code></td><td>{{if .Default}}<code>{{.Default}}</code>{{end}}</td><td>{{.Required}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{end}}`))
+
+// writeHTML writes a table of variables for each struct
+func writeHTML(w io.Writer, structs []structDoc) error {
+	return htmlTemplate.Execute(w, structs)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdown(t *testing.T) {
+	structs, err := load("testdata/config", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeMarkdown(&buf, structs))
+	assert.Equal(t, "# Environment variables\n"+
+		"\n## Config\n\n"+
+		"Config is the configuration of the server\n\n"+
+		"| Variable | Type | Default | Required | Description |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| `HOST` | `string` | `localhost` |  | Host is the address to listen on |\n"+
+		"| `PORT` | `uint16` |  | yes | port to listen on |\n"+
+		"| `TIMEOUT` | `time.Duration` | `5s` |  | timeout of requests |\n"+
+		"| `MODE` | `string` |  |  |  |\n"+
+		"| `TLS_CERT` | `string` |  | if MODE=prod |  |\n"+
+		"| `TAGS` | `[]string` |  |  |  |\n"+
+		"| `DATABASE_URL` | `string` |  | yes |  |\n"+
+		"\n## Database\n\n"+
+		"Database is documented through Config only\n\n"+
+		"| Variable | Type | Default | Required | Description |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| `DATABASE_URL` | `string` |  | yes |  |\n", buf.String())
+}
+
+func TestHTML(t *testing.T) {
+	structs, err := load("testdata/config", []string{"Database"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeHTML(&buf, structs))
+	assert.Contains(t, buf.String(), "<h2>Database</h2>")
+	assert.Contains(t, buf.String(), "<tr><td><code>DATABASE_URL</code></td><td><code>string</code></td><td></td><td>yes</td><td></td></tr>")
+	assert.NotContains(t, buf.String(), "HOST")
+}
+
+func TestUnknownType(t *testing.T) {
+	_, err := load("testdata/config", []string{"Missing"})
+	assert.EqualError(t, err, "type Missing not found in testdata/config")
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// Config is the configuration of the server
+type Config struct {
+	// Host is the address to listen on
+	Host    string        `env:"HOST,default=localhost"`
+	Port    uint16        `env:"PORT,required,default=8080" desc:"port to listen on"`
+	Timeout time.Duration `env:"TIMEOUT,default=5s"` // timeout of requests
+	Mode    string        `env:"MODE,oneof=dev|prod"`
+	Cert    string        `env:"TLS_CERT,required_if=MODE:prod"`
+	Tags    []string      `env:"TAGS,separator=|"`
+
+	Database Database
+	ignored  int
+}
+
+// Database is documented through Config only
+type Database struct {
+	URL string `env:"DATABASE_URL,required,secret"`
+}
+
+type notConfig struct {
+	Name string
+}
@@ -0,0 +1,10 @@
+//go:build !go1.22
+
+package main
+
+import "go/types"
+
+// unalias returns t, go/types doesn't represent aliases before Go 1.22
+func unalias(t types.Type) types.Type {
+	return t
+}
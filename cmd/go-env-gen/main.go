@@ -0,0 +1,553 @@
+// Command go-env-gen generates functions parsing env tagged structs without reflection. For
+// each struct type T it writes a function
+//
+//	func ParseT(src func(string) (string, bool)) (T, error)
+//
+// reading variables from src, such as os.LookupEnv, with straight-line conversion code
+// behaving like env.Parse. Tags and field types are checked when generating.
+//
+// Usage:
+//
+//	go-env-gen -type Config[,...] [-o file] [dir]
+//
+// The output defaults to config_env.go for the first type, e.g. with go generate:
+//
+//	//go:generate go run github.com/stenhagglund/go-env/cmd/go-env-gen -type Config
+//
+// Fields are limited to strings, booleans, numbers, time.Duration, time.Time, os.FileMode,
+// named types of those from any package, slices of them split on a single separator,
+// env.Secret holding them and nested structs, with the options required, default, oneof,
+// separator, allowempty, notempty and secret. Structs implementing Validate are validated. Other types and options
+// are reported as errors, use env.Parse for them.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	env "github.com/stenhagglund/go-env"
+)
+
+const (
+	envPath         = "github.com/stenhagglund/go-env"
+	generatedHeader = "Code generated by go-env-gen. DO NOT EDIT."
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("go-env-gen: ")
+
+	typeNames := flag.String("type", "", "comma separated names of the struct types to generate parse functions for")
+	output := flag.String("o", "", "output file, defaults to <type>_env.go in the package directory")
+	flag.Parse()
+
+	if *typeNames == "" {
+		log.Fatal("the -type flag is required")
+	}
+	dir := "."
+	if flag.NArg() > 1 {
+		log.Fatal("expected at most one package directory")
+	} else if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	src, err := generate(dir, names)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(names[0])+"_env.go")
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generator writes the parse functions of the struct types of a package
+type generator struct {
+	pkg     *types.Package
+	buf     bytes.Buffer
+	imports map[string]string // import path to the name generated code refers to it by
+	starts  int               // number of error counts declared before validating structs
+}
+
+// generatedImports are the names generated code refers to packages by without a qualifier
+var generatedImports = map[string]string{
+	"env":     envPath,
+	"errors":  "errors",
+	"fmt":     "fmt",
+	"slices":  "slices",
+	"strconv": "strconv",
+	"strings": "strings",
+	"time":    "time",
+}
+
+// generate returns the formatted source of the parse functions of the struct types names of
+// the package in dir
+func generate(dir string, names []string) ([]byte, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range buildPkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		// previously generated files may no longer match the structs
+		if len(file.Comments) > 0 && strings.HasPrefix(file.Comments[0].Text(), generatedHeader) {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check(buildPkg.ImportPath, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]string{envPath: "env"}}
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		if err := g.function(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// %s\n\npackage %s\n\nimport (\n", generatedHeader, pkg.Name())
+	// standard library imports first, separated from the others like goimports does
+	var std, other []string
+	for path := range g.imports {
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	for _, path := range std {
+		g.writeImport(&out, path)
+	}
+	out.WriteString("\n")
+	for _, path := range other {
+		g.writeImport(&out, path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.buf.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// function writes the parse function of the struct type obj
+func (g *generator) function(obj *types.TypeName) error {
+	s, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("type %s is not a struct", obj.Name())
+	}
+
+	name := obj.Name()
+	fmt.Fprintf(&g.buf, "\n// Parse%s parses a %s from the variables of src, such as os.LookupEnv, like env.Parse\n", name, name)
+	fmt.Fprintf(&g.buf, "func Parse%s(src func(string) (string, bool)) (%s, error) {\n", name, name)
+	fmt.Fprintf(&g.buf, "var (\ncfg %s\nerrs env.Errors\nvalue string\nok bool\n)\n", name)
+	if err := g.fields(s, obj.Type(), "cfg", ""); err != nil {
+		return fmt.Errorf("%s.%w", name, err)
+	}
+	g.buf.WriteString("\nif len(errs) > 0 {\nreturn cfg, errs\n}\nreturn cfg, nil\n}\n")
+	return nil
+}
+
+// fields writes the code parsing the fields of s, of type t, into dst, followed by a call of
+// Validate if it is implemented and all fields were parsed
+func (g *generator) fields(s *types.Struct, t types.Type, dst, path string) error {
+	validate := implementsValidator(t)
+	var start string
+	if validate {
+		g.starts++
+		start = "start" + strconv.Itoa(g.starts)
+		fmt.Fprintf(&g.buf, "\n%s := len(errs)\n", start)
+	}
+
+	found := false
+	for i := 0; i < s.NumFields(); i++ {
+		field := s.Field(i)
+		tagValue := reflect.StructTag(s.Tag(i)).Get("env")
+		if tagValue == "" {
+			if nested, ok := field.Type().Underlying().(*types.Struct); ok && hasTags(nested) {
+				if err := g.fields(nested, field.Type(), dst+"."+field.Name(), path+field.Name()+"."); err != nil {
+					return fmt.Errorf("%s.%w", field.Name(), err)
+				}
+				found = true
+			}
+			continue
+		}
+
+		if !field.Exported() && field.Pkg() != g.pkg {
+			return fmt.Errorf("%s: unexported field of another package", field.Name())
+		}
+		if err := g.field(field, tagValue, dst+"."+field.Name(), path+field.Name()); err != nil {
+			return fmt.Errorf("%s: %w", field.Name(), err)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("%s has no env tags", types.TypeString(t, g.qualifier))
+	}
+
+	if validate {
+		fieldPath := strings.TrimSuffix(path, ".")
+		fmt.Fprintf(&g.buf, "if len(errs) == %s {\nif err := %s.Validate(); err != nil {\n", start, dst)
+		fmt.Fprintf(&g.buf, "errs = append(errs, env.NewError(\"\", %q, \"\", \"\", err, env.ErrInvalidValue))\n}\n}\n", fieldPath)
+	}
+	return nil
+}
+
+// field writes the code parsing the variable of tagValue into dst, a field of type
+// field.Type() at path
+func (g *generator) field(field *types.Var, tagValue, dst, path string) error {
+	tag, err := env.ParseTag(tagValue)
+	if err != nil {
+		return err
+	}
+	if option := unsupportedOption(tag); option != "" {
+		return fmt.Errorf("option %s is not supported by go-env-gen", option)
+	}
+
+	t, holder := unwrapSecret(field.Type())
+	secret := holder || tag.Secret
+	slice, isSlice := t.Underlying().(*types.Slice)
+	leafType := t
+	if isSlice {
+		leafType = slice.Elem()
+	}
+	leaf, err := g.conversion(leafType)
+	if err != nil {
+		return err
+	}
+
+	// errors of secret fields are replaced as they may contain the value
+	newError := func(option, err, kind string) string {
+		if secret && kind == "env.ErrInvalidValue" {
+			err = "env.RedactError(" + err + ")"
+		}
+		return fmt.Sprintf("errs = append(errs, env.NewError(%q, %q, value, %q, %s, %s))\n", tag.Name, path, option, err, kind)
+	}
+	oneOfError := func(value string) string {
+		g.imports["fmt"] = "fmt"
+		format := "invalid value \"%s\", valid options are: \"" + strings.Join(tag.OneOf, "\", \"") + "\""
+		return newError("oneof", fmt.Sprintf("fmt.Errorf(%s, %s)", strconv.Quote(format), value), "env.ErrInvalidValue")
+	}
+	assign := func(value string) string {
+		if holder {
+			return fmt.Sprintf("%s = env.NewSecret(%s)\n", dst, value)
+		}
+		return fmt.Sprintf("%s = %s\n", dst, value)
+	}
+
+	b := &g.buf
+	fmt.Fprintf(b, "\n// %s\nvalue, ok = src(%q)\n", path, tag.Name)
+	if !tag.AllowEmpty && !tag.NotEmpty {
+		b.WriteString("ok = ok && value != \"\"\n")
+	}
+	if tag.DefaultApplies() {
+		fmt.Fprintf(b, "if !ok {\nvalue, ok = %q, true\n}\n", tag.Default)
+	}
+
+	b.WriteString("switch {\n")
+	if tag.NotEmpty {
+		g.imports["errors"] = "errors"
+		b.WriteString("case ok && value == \"\":\n")
+		b.WriteString(newError("notempty", `errors.New("value must not be empty")`, "env.ErrInvalidValue"))
+	}
+	if tag.Required {
+		b.WriteString("case !ok:\n")
+		b.WriteString(newError("required", "env.ErrRequired", "env.ErrRequired"))
+	}
+	if tag.AllowEmpty {
+		fmt.Fprintf(b, "case ok && value == \"\":\n%s", assign(zeroValue(t, g.qualifier)))
+	}
+	if tag.OneOf != nil && !isSlice {
+		fmt.Fprintf(b, "case ok && %s:\n%s", notOneOf("value", tag.OneOf), oneOfError("value"))
+	}
+	b.WriteString("case ok:\n")
+
+	if !isSlice {
+		if leaf.call == "" {
+			b.WriteString(assign(fmt.Sprintf(leaf.assign, "value")))
+		} else {
+			fmt.Fprintf(b, "v, err := %s\nif err != nil {\n%sbreak\n}\n", fmt.Sprintf(leaf.call, "value"), newError("", "err", "env.ErrInvalidValue"))
+			b.WriteString(assign(fmt.Sprintf(leaf.assign, "v")))
+		}
+		b.WriteString("}\n")
+		return nil
+	}
+
+	g.imports["strings"] = "strings"
+	separator := env.DefaultSeparator
+	if len(tag.Separators) > 0 && tag.Separators[0] != "" {
+		separator = tag.Separators[0]
+	}
+	fmt.Fprintf(b, "parts := strings.Split(value, %q)\n", separator)
+	if tag.OneOf != nil {
+		fmt.Fprintf(b, "if idx := slices.IndexFunc(parts, func(part string) bool { return %s }); idx >= 0 {\n%sbreak\n}\n", notOneOf("part", tag.OneOf), oneOfError("parts[idx]"))
+		g.imports["slices"] = "slices"
+	}
+
+	sliceType := types.TypeString(t, g.qualifier)
+	if leaf.call == "" && types.Identical(t, types.NewSlice(types.Typ[types.String])) {
+		b.WriteString(assign("parts"))
+		b.WriteString("}\n")
+		return nil
+	}
+	fmt.Fprintf(b, "items := make(%s, len(parts))\n", sliceType)
+	if leaf.call == "" {
+		fmt.Fprintf(b, "for idx, part := range parts {\nitems[idx] = %s\n}\n", fmt.Sprintf(leaf.assign, "part"))
+	} else {
+		fmt.Fprintf(b, "var err error\nfor idx, part := range parts {\nvar v %s\nif v, err = %s; err != nil {\nbreak\n}\nitems[idx] = %s\n}\n",
+			leaf.result, fmt.Sprintf(leaf.call, "part"), fmt.Sprintf(leaf.assign, "v"))
+		fmt.Fprintf(b, "if err != nil {\n%sbreak\n}\n", newError("", "err", "env.ErrInvalidValue"))
+	}
+	b.WriteString(assign("items"))
+	b.WriteString("}\n")
+	return nil
+}
+
+// conversion converts a string to a value of a type, by calling call, a format with the
+// string as its argument, returning a result and an error, and converting the result with
+// assign, a format with the result as its argument. Without call the string is converted
+// by assign.
+type conversion struct {
+	call   string
+	result string
+	assign string
+}
+
+// conversion returns the conversion of t, which is a string, boolean or number type,
+// time.Duration, time.Time, os.FileMode or a named type of those
+func (g *generator) conversion(t types.Type) (conversion, error) {
+	typeName := types.TypeString(t, g.qualifier)
+	if named, ok := unalias(t).(*types.Named); ok {
+		if hasValuesMethod(named) {
+			return conversion{}, fmt.Errorf("type %s with a Values method is not supported by go-env-gen", typeName)
+		}
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "time" {
+			g.imports["time"] = "time"
+			switch obj.Name() {
+			case "Duration":
+				return conversion{call: "time.ParseDuration(%s)", result: "time.Duration", assign: "%s"}, nil
+			case "Time":
+				return conversion{call: "time.ParseInLocation(time.RFC3339, %s, time.Local)", result: "time.Time", assign: "%s"}, nil
+			}
+		}
+		// file modes are octal or symbolic rather than decimal
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "io/fs" && obj.Name() == "FileMode" {
+			return conversion{call: "env.ParseFileMode(%s)", result: typeName, assign: typeName + "(%s)"}, nil
+		}
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return conversion{}, fmt.Errorf("type %s is not supported by go-env-gen", typeName)
+	}
+	c := conversion{result: "string"}
+	switch basic.Kind() {
+	case types.String:
+	case types.Bool:
+		c = conversion{call: "strconv.ParseBool(%s)", result: "bool"}
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		c = conversion{call: fmt.Sprintf("strconv.ParseInt(%%s, 10, %d)", bitSize(basic.Kind())), result: "int64"}
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		c = conversion{call: fmt.Sprintf("strconv.ParseUint(%%s, 10, %d)", bitSize(basic.Kind())), result: "uint64"}
+	case types.Float32, types.Float64:
+		c = conversion{call: fmt.Sprintf("strconv.ParseFloat(%%s, %d)", bitSize(basic.Kind())), result: "float64"}
+	default:
+		return conversion{}, fmt.Errorf("type %s is not supported by go-env-gen", typeName)
+	}
+	if c.call != "" {
+		g.imports["strconv"] = "strconv"
+	}
+	c.assign = typeName + "(%s)"
+	if typeName == c.result {
+		c.assign = "%s"
+	}
+	return c, nil
+}
+
+// bitSize returns the bit size Parse converts values of kind with, which is 32 for int and
+// uint
+func bitSize(kind types.BasicKind) int {
+	switch kind {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int64, types.Uint64, types.Float64:
+		return 64
+	}
+	return 32
+}
+
+// writeImport writes the import of path, naming it if generated code refers to it by another
+// name than the last element of path
+func (g *generator) writeImport(out *bytes.Buffer, path string) {
+	if name := g.imports[path]; name != path[strings.LastIndex(path, "/")+1:] {
+		fmt.Fprintf(out, "\t%s %q\n", name, path)
+		return
+	}
+	fmt.Fprintf(out, "\t%q\n", path)
+}
+
+// qualifier returns the name generated code refers to pkg by and imports it, aliasing it if
+// its name is taken by another import or a declaration of the generated package
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	if name, ok := g.imports[pkg.Path()]; ok {
+		return name
+	}
+	name := pkg.Name()
+	for i := 2; g.nameTaken(name, pkg.Path()); i++ {
+		name = pkg.Name() + strconv.Itoa(i)
+	}
+	g.imports[pkg.Path()] = name
+	return name
+}
+
+// nameTaken reports whether generated code can't refer to the package path by name
+func (g *generator) nameTaken(name, path string) bool {
+	if generatedPath, ok := generatedImports[name]; ok && generatedPath != path {
+		return true
+	}
+	for importPath, importName := range g.imports {
+		if importName == name && importPath != path {
+			return true
+		}
+	}
+	return g.pkg.Scope().Lookup(name) != nil
+}
+
+// unsupportedOption returns the first option of tag the generator doesn't implement
+func unsupportedOption(tag env.Tag) string {
+	switch {
+	case len(tag.Conditions) > 0:
+		return tag.Conditions[0].Option
+	case tag.Type != "":
+		return "type"
+	case tag.Format != "":
+		return "format"
+	case len(tag.Separators) > 1:
+		return "separators"
+	case tag.Quoted:
+		return "quoted"
+	case tag.Trim:
+		return "trim"
+	case tag.Group != "":
+		return "group"
+	case tag.Min != "":
+		return "min"
+	case tag.Max != "":
+		return "max"
+	case tag.Len != "":
+		return "len"
+	case tag.Pattern != "":
+		return "pattern"
+	case tag.MinItems != "":
+		return "minitems"
+	case tag.MaxItems != "":
+		return "maxitems"
+	}
+	return ""
+}
+
+// notOneOf returns the condition of value not being one of values
+func notOneOf(value string, values []string) string {
+	conditions := make([]string, len(values))
+	for idx, v := range values {
+		conditions[idx] = fmt.Sprintf("%s != %q", value, v)
+	}
+	return strings.Join(conditions, " && ")
+}
+
+// zeroValue returns the zero value literal of t
+func zeroValue(t types.Type, qualifier types.Qualifier) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		}
+		return "0"
+	case *types.Slice:
+		return "nil"
+	}
+	return types.TypeString(t, qualifier) + "{}"
+}
+
+// unwrapSecret returns the type held by t if it is an env.Secret
+func unwrapSecret(t types.Type) (types.Type, bool) {
+	named, ok := unalias(t).(*types.Named)
+	if !ok || named.TypeArgs().Len() != 1 {
+		return t, false
+	}
+	if obj := named.Obj(); obj.Pkg() == nil || obj.Pkg().Path() != envPath || obj.Name() != "Secret" {
+		return t, false
+	}
+	return named.TypeArgs().At(0), true
+}
+
+// hasTags reports whether s or its nested structs have fields with an env tag
+func hasTags(s *types.Struct) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if reflect.StructTag(s.Tag(i)).Get("env") != "" {
+			return true
+		}
+		if nested, ok := s.Field(i).Type().Underlying().(*types.Struct); ok && hasTags(nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// implementsValidator reports whether pointers to t have a Validate() error method
+func implementsValidator(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "Validate")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 && sig.Results().At(0).Type().String() == "error"
+}
+
+// hasValuesMethod reports whether t has a Values method, which Parse checks values against
+func hasValuesMethod(t *types.Named) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, "Values")
+	_, ok := obj.(*types.Func)
+	return ok
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	env "github.com/stenhagglund/go-env"
+	"github.com/stenhagglund/go-env/cmd/go-env-gen/testdata/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	assert := require.New(t)
+
+	expected, err := os.ReadFile("testdata/config/config_env.go")
+	assert.Nil(err)
+	src, err := generate("testdata/config", []string{"Config"})
+	assert.Nil(err)
+	assert.Equal(string(expected), string(src))
+
+	// the error counts of structs validated at different paths don't clash
+	src, err = generate("testdata/nested", []string{"Config"})
+	assert.Nil(err)
+	assert.Contains(string(src), "start1 := len(errs)")
+	assert.Contains(string(src), "start2 := len(errs)")
+
+	_, err = generate("testdata/unsupported", []string{"Config"})
+	assert.EqualError(err, "Config.Port: option min is not supported by go-env-gen")
+	_, err = generate("testdata/unsupported", []string{"Limits"})
+	assert.EqualError(err, "Limits.Values: type map[string]int is not supported by go-env-gen")
+	_, err = generate("testdata/unsupported", []string{"Missing"})
+	assert.EqualError(err, "type Missing not found in testdata/unsupported")
+}
+
+// TestGeneratedParse compares the generated parser of testdata/config with env.Parse
+func TestGeneratedParse(t *testing.T) {
+	assert := require.New(t)
+
+	environ := os.Environ()
+	defer func() {
+		os.Clearenv()
+		for _, e := range environ {
+			name, value, _ := strings.Cut(e, "=")
+			os.Setenv(name, value)
+		}
+	}()
+
+	for _, vars := range []map[string]string{
+		{},
+		{"GO_ENV_GEN_PORT": "8080", "GO_ENV_GEN_PASSWORD": "secret"},
+		{
+			"GO_ENV_GEN_HOST": "example.com", "GO_ENV_GEN_PORT": "443", "GO_ENV_GEN_TIMEOUT": "1m", "GO_ENV_GEN_WAIT": "2m",
+			"GO_ENV_GEN_STARTED": "2024-01-02T03:04:05Z", "GO_ENV_GEN_DEBUG": "true", "GO_ENV_GEN_RATIO": "0.5",
+			"GO_ENV_GEN_LEVEL": "-3", "GO_ENV_GEN_LOG_LEVEL": "-4", "GO_ENV_GEN_FORMATS": "json,text", "GO_ENV_GEN_PERM": "rw-r-----", "GO_ENV_GEN_MODE": "prod", "GO_ENV_GEN_MOTD": "", "GO_ENV_GEN_NAME": "api",
+			"GO_ENV_GEN_TAGS": "a:b", "GO_ENV_GEN_PORTS": "80,443", "GO_ENV_GEN_PIN": "1234", "GO_ENV_GEN_PASSWORD": "secret",
+			"GO_ENV_GEN_DB_URL": "postgres://db", "GO_ENV_GEN_DB_READ_ONLY": "1",
+		},
+		{
+			"GO_ENV_GEN_PORT": "70000", "GO_ENV_GEN_TIMEOUT": "5", "GO_ENV_GEN_WAIT": "5", "GO_ENV_GEN_MODE": "test", "GO_ENV_GEN_NAME": "",
+			"GO_ENV_GEN_PORTS": "80,8080", "GO_ENV_GEN_PIN": "abc", "GO_ENV_GEN_LOG_LEVEL": "warn", "GO_ENV_GEN_FORMATS": "xml", "GO_ENV_GEN_PERM": "0999",
+			"GO_ENV_GEN_DB_READ_ONLY": "true",
+		},
+		{"GO_ENV_GEN_PORT": "", "GO_ENV_GEN_PORTS": "80,x", "GO_ENV_GEN_LEVEL": "3000000000", "GO_ENV_GEN_DB_READ_ONLY": "yes"},
+	} {
+		os.Clearenv()
+		for name, value := range vars {
+			os.Setenv(name, value)
+		}
+
+		var expected config.Config
+		expectedErr := env.Parse(&expected)
+		cfg, err := config.ParseConfig(os.LookupEnv)
+		assert.Equal(expected, cfg)
+		if expectedErr == nil {
+			assert.Nil(err)
+			continue
+		}
+		assert.EqualError(err, expectedErr.Error())
+		assert.Equal(expectedErr.(env.Errors), err.(env.Errors))
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	env "github.com/stenhagglund/go-env"
+	logformat "github.com/stenhagglund/go-env/cmd/go-env-gen/testdata/config/slog"
+)
+
+//go:generate go run github.com/stenhagglund/go-env/cmd/go-env-gen -type Config
+
+type Level int
+
+type Wait = time.Duration
+
+type Config struct {
+	Host     string             `env:"GO_ENV_GEN_HOST,default=localhost"`
+	Port     uint16             `env:"GO_ENV_GEN_PORT,required"`
+	Timeout  time.Duration      `env:"GO_ENV_GEN_TIMEOUT,default=5s"`
+	Wait     Wait               `env:"GO_ENV_GEN_WAIT,default=1s"`
+	Started  time.Time          `env:"GO_ENV_GEN_STARTED"`
+	Debug    bool               `env:"GO_ENV_GEN_DEBUG"`
+	Ratio    float64            `env:"GO_ENV_GEN_RATIO"`
+	Level    Level              `env:"GO_ENV_GEN_LEVEL"`
+	LogLevel slog.Level         `env:"GO_ENV_GEN_LOG_LEVEL,default=4"`
+	Formats  []logformat.Format `env:"GO_ENV_GEN_FORMATS,oneof=text|json"`
+	Perm     os.FileMode        `env:"GO_ENV_GEN_PERM,default=0640"`
+	Mode     string             `env:"GO_ENV_GEN_MODE,oneof=dev|prod"`
+	Motd     string             `env:"GO_ENV_GEN_MOTD,allowempty,default=hello"`
+	Name     string             `env:"GO_ENV_GEN_NAME,notempty"`
+	Tags     []string           `env:"GO_ENV_GEN_TAGS,separator=:"`
+	Ports    []int              `env:"GO_ENV_GEN_PORTS,oneof=80|443"`
+	Pin      int                `env:"GO_ENV_GEN_PIN,secret"`
+	Password env.Secret[string] `env:"GO_ENV_GEN_PASSWORD,required,default=changeme"`
+
+	DB Database
+}
+
+type Database struct {
+	URL      string `env:"GO_ENV_GEN_DB_URL"`
+	ReadOnly bool   `env:"GO_ENV_GEN_DB_READ_ONLY"`
+}
+
+func (d *Database) Validate() error {
+	if d.ReadOnly && d.URL == "" {
+		return errors.New("read only requires a URL")
+	}
+	return nil
+}
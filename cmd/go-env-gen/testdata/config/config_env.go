@@ -0,0 +1,308 @@
+// Code generated by go-env-gen. DO NOT EDIT.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	env "github.com/stenhagglund/go-env"
+	slog2 "github.com/stenhagglund/go-env/cmd/go-env-gen/testdata/config/slog"
+)
+
+// ParseConfig parses a Config from the variables of src, such as os.LookupEnv, like env.Parse
+func ParseConfig(src func(string) (string, bool)) (Config, error) {
+	var (
+		cfg   Config
+		errs  env.Errors
+		value string
+		ok    bool
+	)
+
+	// Host
+	value, ok = src("GO_ENV_GEN_HOST")
+	ok = ok && value != ""
+	if !ok {
+		value, ok = "localhost", true
+	}
+	switch {
+	case ok:
+		cfg.Host = value
+	}
+
+	// Port
+	value, ok = src("GO_ENV_GEN_PORT")
+	ok = ok && value != ""
+	switch {
+	case !ok:
+		errs = append(errs, env.NewError("GO_ENV_GEN_PORT", "Port", value, "required", env.ErrRequired, env.ErrRequired))
+	case ok:
+		v, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_PORT", "Port", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Port = uint16(v)
+	}
+
+	// Timeout
+	value, ok = src("GO_ENV_GEN_TIMEOUT")
+	ok = ok && value != ""
+	if !ok {
+		value, ok = "5s", true
+	}
+	switch {
+	case ok:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_TIMEOUT", "Timeout", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Timeout = v
+	}
+
+	// Wait
+	value, ok = src("GO_ENV_GEN_WAIT")
+	ok = ok && value != ""
+	if !ok {
+		value, ok = "1s", true
+	}
+	switch {
+	case ok:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_WAIT", "Wait", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Wait = v
+	}
+
+	// Started
+	value, ok = src("GO_ENV_GEN_STARTED")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		v, err := time.ParseInLocation(time.RFC3339, value, time.Local)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_STARTED", "Started", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Started = v
+	}
+
+	// Debug
+	value, ok = src("GO_ENV_GEN_DEBUG")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_DEBUG", "Debug", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Debug = v
+	}
+
+	// Ratio
+	value, ok = src("GO_ENV_GEN_RATIO")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_RATIO", "Ratio", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Ratio = v
+	}
+
+	// Level
+	value, ok = src("GO_ENV_GEN_LEVEL")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_LEVEL", "Level", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Level = Level(v)
+	}
+
+	// LogLevel
+	value, ok = src("GO_ENV_GEN_LOG_LEVEL")
+	ok = ok && value != ""
+	if !ok {
+		value, ok = "4", true
+	}
+	switch {
+	case ok:
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_LOG_LEVEL", "LogLevel", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.LogLevel = slog.Level(v)
+	}
+
+	// Formats
+	value, ok = src("GO_ENV_GEN_FORMATS")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		parts := strings.Split(value, ",")
+		if idx := slices.IndexFunc(parts, func(part string) bool { return part != "text" && part != "json" }); idx >= 0 {
+			errs = append(errs, env.NewError("GO_ENV_GEN_FORMATS", "Formats", value, "oneof", fmt.Errorf("invalid value \"%s\", valid options are: \"text\", \"json\"", parts[idx]), env.ErrInvalidValue))
+			break
+		}
+		items := make([]slog2.Format, len(parts))
+		for idx, part := range parts {
+			items[idx] = slog2.Format(part)
+		}
+		cfg.Formats = items
+	}
+
+	// Perm
+	value, ok = src("GO_ENV_GEN_PERM")
+	ok = ok && value != ""
+	if !ok {
+		value, ok = "0640", true
+	}
+	switch {
+	case ok:
+		v, err := env.ParseFileMode(value)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_PERM", "Perm", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Perm = os.FileMode(v)
+	}
+
+	// Mode
+	value, ok = src("GO_ENV_GEN_MODE")
+	ok = ok && value != ""
+	switch {
+	case ok && value != "dev" && value != "prod":
+		errs = append(errs, env.NewError("GO_ENV_GEN_MODE", "Mode", value, "oneof", fmt.Errorf("invalid value \"%s\", valid options are: \"dev\", \"prod\"", value), env.ErrInvalidValue))
+	case ok:
+		cfg.Mode = value
+	}
+
+	// Motd
+	value, ok = src("GO_ENV_GEN_MOTD")
+	if !ok {
+		value, ok = "hello", true
+	}
+	switch {
+	case ok && value == "":
+		cfg.Motd = ""
+	case ok:
+		cfg.Motd = value
+	}
+
+	// Name
+	value, ok = src("GO_ENV_GEN_NAME")
+	switch {
+	case ok && value == "":
+		errs = append(errs, env.NewError("GO_ENV_GEN_NAME", "Name", value, "notempty", errors.New("value must not be empty"), env.ErrInvalidValue))
+	case ok:
+		cfg.Name = value
+	}
+
+	// Tags
+	value, ok = src("GO_ENV_GEN_TAGS")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		parts := strings.Split(value, ":")
+		cfg.Tags = parts
+	}
+
+	// Ports
+	value, ok = src("GO_ENV_GEN_PORTS")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		parts := strings.Split(value, ",")
+		if idx := slices.IndexFunc(parts, func(part string) bool { return part != "80" && part != "443" }); idx >= 0 {
+			errs = append(errs, env.NewError("GO_ENV_GEN_PORTS", "Ports", value, "oneof", fmt.Errorf("invalid value \"%s\", valid options are: \"80\", \"443\"", parts[idx]), env.ErrInvalidValue))
+			break
+		}
+		items := make([]int, len(parts))
+		var err error
+		for idx, part := range parts {
+			var v int64
+			if v, err = strconv.ParseInt(part, 10, 32); err != nil {
+				break
+			}
+			items[idx] = int(v)
+		}
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_PORTS", "Ports", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.Ports = items
+	}
+
+	// Pin
+	value, ok = src("GO_ENV_GEN_PIN")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_PIN", "Pin", value, "", env.RedactError(err), env.ErrInvalidValue))
+			break
+		}
+		cfg.Pin = int(v)
+	}
+
+	// Password
+	value, ok = src("GO_ENV_GEN_PASSWORD")
+	ok = ok && value != ""
+	switch {
+	case !ok:
+		errs = append(errs, env.NewError("GO_ENV_GEN_PASSWORD", "Password", value, "required", env.ErrRequired, env.ErrRequired))
+	case ok:
+		cfg.Password = env.NewSecret(value)
+	}
+
+	start1 := len(errs)
+
+	// DB.URL
+	value, ok = src("GO_ENV_GEN_DB_URL")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		cfg.DB.URL = value
+	}
+
+	// DB.ReadOnly
+	value, ok = src("GO_ENV_GEN_DB_READ_ONLY")
+	ok = ok && value != ""
+	switch {
+	case ok:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, env.NewError("GO_ENV_GEN_DB_READ_ONLY", "DB.ReadOnly", value, "", err, env.ErrInvalidValue))
+			break
+		}
+		cfg.DB.ReadOnly = v
+	}
+	if len(errs) == start1 {
+		if err := cfg.DB.Validate(); err != nil {
+			errs = append(errs, env.NewError("", "DB", "", "", err, env.ErrInvalidValue))
+		}
+	}
+
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+	return cfg, nil
+}
@@ -0,0 +1,4 @@
+// Package slog clashes with the name of log/slog to test aliased imports
+package slog
+
+type Format string
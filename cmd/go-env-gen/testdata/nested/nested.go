@@ -0,0 +1,19 @@
+package nested
+
+// Config nests validated structs at the paths A.B and AB
+type Config struct {
+	A  Outer
+	AB Inner
+}
+
+type Outer struct {
+	B Inner
+}
+
+type Inner struct {
+	Value string `env:"VALUE"`
+}
+
+func (i *Inner) Validate() error {
+	return nil
+}
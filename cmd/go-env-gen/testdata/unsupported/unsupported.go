@@ -0,0 +1,9 @@
+package unsupported
+
+type Config struct {
+	Port int `env:"PORT,min=1"`
+}
+
+type Limits struct {
+	Values map[string]int `env:"LIMITS"`
+}
@@ -0,0 +1,10 @@
+//go:build go1.22
+
+package main
+
+import "go/types"
+
+// unalias returns the type t denotes if it is an alias
+func unalias(t types.Type) types.Type {
+	return types.Unalias(t)
+}
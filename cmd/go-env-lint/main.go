@@ -0,0 +1,424 @@
+// Command go-env-lint checks the env struct tags of Go packages for mistakes which Parse
+// would only report at runtime: invalid options and empty names, variables read by more than
+// one field of a struct, type= options on fields which aren't bytes or runes, unsupported
+// field types and defaults which fail to parse or validate.
+//
+// Defaults of format=json fields are decoded into a type built like the field type. Types
+// with their own UnmarshalJSON or UnmarshalText methods, embedded fields, non-empty interfaces
+// or recursion can't be built, their defaults are only checked to be valid JSON.
+//
+// Usage:
+//
+//	go-env-lint [dir|dir/...]...
+//
+// Problems are printed as file:line:col: message, and the exit status is 1 if there are any.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	env "github.com/stenhagglund/go-env"
+)
+
+const envPath = "github.com/stenhagglund/go-env"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("go-env-lint: ")
+
+	patterns := os.Args[1:]
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	var dirs []string
+	for _, pattern := range patterns {
+		matches, err := expand(pattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dirs = append(dirs, matches...)
+	}
+
+	failed := false
+	for _, dir := range dirs {
+		problems, err := lint(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		failed = failed || len(problems) > 0
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// expand returns the directory of pattern, or every directory below it holding Go files if
+// it ends in /..., skipping testdata, vendor and hidden directories
+func expand(pattern string) ([]string, error) {
+	root, ok := strings.CutSuffix(pattern, "/...")
+	if !ok {
+		return []string{pattern}, nil
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if name := d.Name(); path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if _, err := build.ImportDir(path, 0); err == nil {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// problem is a mistake found at a position in the source
+type problem struct {
+	pos     token.Position
+	message string
+}
+
+func (p problem) String() string {
+	return fmt.Sprintf("%s: %s", p.pos, p.message)
+}
+
+// linter collects the problems of a type checked package
+type linter struct {
+	fset     *token.FileSet
+	info     *types.Info
+	problems map[problem]bool
+}
+
+// lint returns the problems of the package in dir sorted by position
+func lint(dir string) ([]problem, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{fset: token.NewFileSet(), problems: map[problem]bool{}}
+	var files []*ast.File
+	for _, name := range buildPkg.GoFiles {
+		file, err := parser.ParseFile(l.fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	// fields of types which can't be checked are only checked for their tags
+	l.info = &types.Info{Types: map[ast.Expr]types.TypeAndValue{}, Defs: map[*ast.Ident]types.Object{}}
+	conf := types.Config{Importer: importer.ForCompiler(l.fset, "source", nil), Error: func(error) {}}
+	conf.Check(buildPkg.ImportPath, l.fset, files, l.info)
+
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if s, ok := n.(*ast.StructType); ok {
+				l.checkFields(s)
+			}
+			return true
+		})
+	}
+
+	// duplicates are checked from each named struct, as nested structs are read with it
+	for ident, obj := range l.info.Defs {
+		if obj, ok := obj.(*types.TypeName); ok && ident.Name != "_" {
+			if s, ok := obj.Type().Underlying().(*types.Struct); ok {
+				l.checkDuplicates(s, map[string]string{}, obj.Name())
+			}
+		}
+	}
+
+	problems := make([]problem, 0, len(l.problems))
+	for p := range l.problems {
+		problems = append(problems, p)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		a, b := problems[i].pos, problems[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return problems[i].message < problems[j].message
+	})
+	return problems, nil
+}
+
+func (l *linter) report(pos token.Pos, format string, args ...interface{}) {
+	l.problems[problem{pos: l.fset.Position(pos), message: fmt.Sprintf(format, args...)}] = true
+}
+
+// checkFields checks the env tag of every field of s against the type of the field
+func (l *linter) checkFields(s *ast.StructType) {
+	for _, field := range s.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tagValue, ok := lookupTag(field.Tag.Value, "env")
+		if !ok {
+			continue
+		}
+
+		tag, err := env.ParseTag(tagValue)
+		if err != nil {
+			l.report(field.Tag.Pos(), "%s", err)
+			continue
+		}
+
+		t := l.info.TypeOf(field.Type)
+		if t == nil || t == types.Typ[types.Invalid] {
+			continue
+		}
+		t, secret := unwrapSecret(t)
+		if tag.Type != "" && tag.Format == "" && !aliasable(t, tag.Type) {
+			l.report(field.Tag.Pos(), "%s: type=%s has no effect on a field of type %s", tag.Name, tag.Type, t)
+		}
+
+		rt, ok := reflectType(t)
+		if tag.Format != "" {
+			rt, ok = jsonType(t, map[*types.Named]bool{})
+		}
+		if !ok && tag.Format == "" {
+			l.report(field.Type.Pos(), "%s: unsupported type %s", tag.Name, t)
+			continue
+		}
+		if !ok {
+			rt = emptyInterface
+		}
+		if secret {
+			tagValue += ",secret"
+		}
+		if err := env.CheckTag(rt, tagValue); err != nil {
+			l.report(field.Tag.Pos(), "%s", err)
+		}
+	}
+}
+
+// checkDuplicates reports fields reading a variable of names, which maps the variables read
+// by the fields seen before to the path of the field, including nested structs
+func (l *linter) checkDuplicates(s *types.Struct, names map[string]string, path string) {
+	for i := 0; i < s.NumFields(); i++ {
+		field := s.Field(i)
+		tagValue, ok := reflect.StructTag(s.Tag(i)).Lookup("env")
+		if !ok || tagValue == "" {
+			if nested, ok := field.Type().Underlying().(*types.Struct); ok {
+				l.checkDuplicates(nested, names, path+"."+field.Name())
+			}
+			continue
+		}
+
+		tag, err := env.ParseTag(tagValue)
+		if err != nil {
+			continue
+		}
+		fieldPath := path + "." + field.Name()
+		if other, ok := names[tag.Name]; ok {
+			l.report(field.Pos(), "%s: variable of %s is also read by %s", tag.Name, fieldPath, other)
+			continue
+		}
+		names[tag.Name] = fieldPath
+	}
+}
+
+// lookupTag returns the value of key in the struct tag literal lit
+func lookupTag(lit, key string) (string, bool) {
+	tag, err := strconv.Unquote(lit)
+	if err != nil {
+		return "", false
+	}
+	return reflect.StructTag(tag).Lookup(key)
+}
+
+// unwrapSecret returns the type held by t if it is an env.Secret
+func unwrapSecret(t types.Type) (types.Type, bool) {
+	t = unalias(t)
+	named, ok := t.(*types.Named)
+	if !ok || named.TypeArgs().Len() != 1 {
+		return t, false
+	}
+	if obj := named.Obj(); obj.Pkg() == nil || obj.Pkg().Path() != envPath || obj.Name() != "Secret" {
+		return t, false
+	}
+	return named.TypeArgs().At(0), true
+}
+
+// aliasable reports whether the type=byte or type=rune option aliasType applies to t, a
+// byte or rune, or a slice of them at any level
+func aliasable(t types.Type, aliasType string) bool {
+	for {
+		slice, ok := t.Underlying().(*types.Slice)
+		if !ok {
+			break
+		}
+		t = slice.Elem()
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	if aliasType == "byte" {
+		return basic.Kind() == types.Uint8
+	}
+	return basic.Kind() == types.Int32
+}
+
+var basicTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:       reflect.TypeOf(false),
+	types.Int:        reflect.TypeOf(int(0)),
+	types.Int8:       reflect.TypeOf(int8(0)),
+	types.Int16:      reflect.TypeOf(int16(0)),
+	types.Int32:      reflect.TypeOf(int32(0)),
+	types.Int64:      reflect.TypeOf(int64(0)),
+	types.Uint:       reflect.TypeOf(uint(0)),
+	types.Uint8:      reflect.TypeOf(uint8(0)),
+	types.Uint16:     reflect.TypeOf(uint16(0)),
+	types.Uint32:     reflect.TypeOf(uint32(0)),
+	types.Uint64:     reflect.TypeOf(uint64(0)),
+	types.Uintptr:    reflect.TypeOf(uintptr(0)),
+	types.Float32:    reflect.TypeOf(float32(0)),
+	types.Float64:    reflect.TypeOf(float64(0)),
+	types.Complex64:  reflect.TypeOf(complex64(0)),
+	types.Complex128: reflect.TypeOf(complex128(0)),
+	types.String:     reflect.TypeOf(""),
+}
+
+// namedTypes are the named types Parse handles other than by their underlying type
+var namedTypes = map[string]reflect.Type{
+	"time.Duration":  reflect.TypeOf(time.Duration(0)),
+	"time.Time":      reflect.TypeOf(time.Time{}),
+	"io/fs.FileMode": reflect.TypeOf(os.FileMode(0)),
+	"regexp.Regexp":  reflect.TypeOf(regexp.Regexp{}),
+}
+
+// reflectType returns a type Parse handles like t, or false if t can't be represented, which
+// is the case for types Parse doesn't support other than basic types
+func reflectType(t types.Type) (reflect.Type, bool) {
+	switch t := unalias(t).(type) {
+	case *types.Named:
+		if obj := t.Obj(); obj.Pkg() != nil {
+			if rt, ok := namedTypes[obj.Pkg().Path()+"."+obj.Name()]; ok {
+				return rt, true
+			}
+		}
+		return reflectType(t.Underlying())
+	case *types.Basic:
+		rt, ok := basicTypes[t.Kind()]
+		return rt, ok
+	case *types.Slice:
+		elem, ok := reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.SliceOf(elem), true
+	case *types.Pointer:
+		if elem, ok := reflectType(t.Elem()); ok && elem == namedTypes["regexp.Regexp"] {
+			return reflect.PtrTo(elem), true
+		}
+	}
+	return nil, false
+}
+
+var emptyInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// jsonType returns a type encoding/json decodes like t, or false if t can't be represented,
+// which is the case for named types with their own UnmarshalJSON or UnmarshalText methods,
+// embedded fields, non-empty interfaces and recursive types. visiting holds the named types
+// t is nested in.
+func jsonType(t types.Type, visiting map[*types.Named]bool) (reflect.Type, bool) {
+	switch t := unalias(t).(type) {
+	case *types.Named:
+		if obj := t.Obj(); obj.Pkg() != nil {
+			if rt, ok := namedTypes[obj.Pkg().Path()+"."+obj.Name()]; ok {
+				return rt, true
+			}
+		}
+		methods := types.NewMethodSet(types.NewPointer(t))
+		for _, name := range []string{"UnmarshalJSON", "UnmarshalText"} {
+			if methods.Lookup(nil, name) != nil {
+				return nil, false
+			}
+		}
+		if visiting[t] {
+			return nil, false
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return jsonType(t.Underlying(), visiting)
+	case *types.Basic:
+		rt, ok := basicTypes[t.Kind()]
+		return rt, ok
+	case *types.Pointer:
+		elem, ok := jsonType(t.Elem(), visiting)
+		if !ok {
+			return nil, false
+		}
+		return reflect.PtrTo(elem), true
+	case *types.Slice:
+		elem, ok := jsonType(t.Elem(), visiting)
+		if !ok {
+			return nil, false
+		}
+		return reflect.SliceOf(elem), true
+	case *types.Array:
+		elem, ok := jsonType(t.Elem(), visiting)
+		if !ok {
+			return nil, false
+		}
+		return reflect.ArrayOf(int(t.Len()), elem), true
+	case *types.Map:
+		key, keyOK := jsonType(t.Key(), visiting)
+		elem, elemOK := jsonType(t.Elem(), visiting)
+		if !keyOK || !elemOK {
+			return nil, false
+		}
+		return reflect.MapOf(key, elem), true
+	case *types.Struct:
+		var fields []reflect.StructField
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			if field.Embedded() {
+				return nil, false
+			}
+			if !field.Exported() {
+				continue
+			}
+			rt, ok := jsonType(field.Type(), visiting)
+			if !ok {
+				return nil, false
+			}
+			fields = append(fields, reflect.StructField{Name: field.Name(), Type: rt, Tag: reflect.StructTag(t.Tag(i))})
+		}
+		return reflect.StructOf(fields), true
+	case *types.Interface:
+		if t.Empty() {
+			return emptyInterface, true
+		}
+	}
+	return nil, false
+}
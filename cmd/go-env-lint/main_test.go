@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	assert := require.New(t)
+
+	problems, err := lint("testdata/bad")
+	assert.Nil(err)
+
+	messages := make([]string, len(problems))
+	for idx, p := range problems {
+		messages[idx] = p.String()
+	}
+	assert.Equal([]string{
+		"testdata/bad/bad.go:6:25: HOST: unknown option defualt=localhost",
+		"testdata/bad/bad.go:7:25: env variable name cannot be empty",
+		"testdata/bad/bad.go:8:25: PORT: strconv.ParseUint: parsing \"http\": invalid syntax",
+		"testdata/bad/bad.go:10:25: WAIT: time: missing unit in duration \"5\"",
+		"testdata/bad/bad.go:11:25: SEP: type=byte has no effect on a field of type string",
+		"testdata/bad/bad.go:13:10: LIMITS: unsupported type map[string]int",
+		"testdata/bad/bad.go:15:25: LEVEL: value must be at most 10 but was 20",
+		"testdata/bad/bad.go:22:2: DATABASE_URL: variable of Config.Secondary.URL is also read by Config.Primary.URL",
+		"testdata/bad/bad.go:26:14: PORT: strconv.ParseInt: parsing \"x\": invalid syntax",
+		"testdata/bad/bad.go:27:14: RETRIES: unknown option mint=3",
+		"testdata/bad/bad.go:37:16: ALIAS_WAIT: time: missing unit in duration \"5\"",
+		"testdata/bad/bad.go:41:25: WEIGHTS: json: cannot unmarshal array into Go value of type map[string]int",
+		"testdata/bad/bad.go:42:25: JSON_LIMITS: json: cannot unmarshal string into Go struct field .max of type int",
+	}, messages)
+}
@@ -0,0 +1,52 @@
+package bad
+
+import "time"
+
+type Config struct {
+	Host    string         `env:"HOST,defualt=localhost"`
+	Name    string         `env:",required"`
+	Port    uint16         `env:"PORT,default=http"`
+	Timeout time.Duration  `env:"TIMEOUT,default=5s"`
+	Wait    time.Duration  `env:"WAIT,default=5"`
+	Sep     string         `env:"SEP,type=byte"`
+	Seps    [][]byte       `env:"SEPS,type=byte"`
+	Limits  map[string]int `env:"LIMITS"`
+	Rules   map[string]int `env:"RULES,format=json,default={}"`
+	Level   int            `env:"LEVEL,default=20,max=10"`
+
+	Primary   Database
+	Secondary Database
+}
+
+type Database struct {
+	URL string `env:"DATABASE_URL"`
+}
+
+var _ = struct {
+	Port    int `env:"PORT,default=x"`
+	Retries int `env:"RETRIES,mint=3"`
+}{}
+
+type (
+	port     = uint16
+	duration = time.Duration
+)
+
+type Aliases struct {
+	Port port     `env:"ALIAS_PORT,default=8080"`
+	Wait duration `env:"ALIAS_WAIT,default=5"`
+}
+
+type JSON struct {
+	Weights map[string]int `env:"WEIGHTS,format=json,default=[1]"`
+	Limits  limits         `env:"JSON_LIMITS,format=json,default={\"max\":\"x\"}"`
+	Tree    tree           `env:"JSON_TREE,format=json,default={\"children\":[{}]}"`
+}
+
+type limits struct {
+	Max int `json:"max"`
+}
+
+type tree struct {
+	Children []tree `json:"children"`
+}
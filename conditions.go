@@ -0,0 +1,96 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	constRequiredIf     = "required_if"
+	constRequiredUnless = "required_unless"
+	constRequiredWith   = "required_with"
+)
+
+// Condition makes a field required depending on the value of another variable
+type Condition struct {
+	Option string   // one of required_if, required_unless or required_with
+	Var    string   // the variable the condition depends on
+	Values []string // the values of required_if and required_unless
+}
+
+// newConditions parses the value of a required_if=VAR:a|b, required_unless=VAR:a|b or
+// required_with=VAR|VAR2 option
+func newConditions(option, value string) ([]Condition, error) {
+	if option == constRequiredWith {
+		var conditions []Condition
+		for _, name := range strings.Split(value, "|") {
+			if name == "" {
+				return nil, fmt.Errorf("invalid condition \"%s\", expected %s=VAR", value, option)
+			}
+			conditions = append(conditions, Condition{Option: option, Var: name})
+		}
+		return conditions, nil
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid condition \"%s\", expected %s=VAR:value", value, option)
+	}
+	return []Condition{{Option: option, Var: parts[0], Values: strings.Split(parts[1], "|")}}, nil
+}
+
+// requires reports whether the condition makes its field required, given the resolved
+// value of the variable it depends on and whether it's set
+func (c Condition) requires(value string, ok bool) bool {
+	switch c.Option {
+	case constRequiredIf:
+		return containsString(c.Values, value)
+	case constRequiredUnless:
+		return !containsString(c.Values, value)
+	}
+	return ok
+}
+
+// error describes why the field of an empty value was required
+func (c Condition) error() error {
+	values := strings.Join(c.Values, "\", \"")
+	switch c.Option {
+	case constRequiredIf:
+		return fmt.Errorf("value is required when %s is \"%s\"", c.Var, values)
+	case constRequiredUnless:
+		return fmt.Errorf("value is required unless %s is \"%s\"", c.Var, values)
+	}
+	return fmt.Errorf("value is required when %s is set", c.Var)
+}
+
+// resolve returns the value of the variable name and whether it's set, looked up like the
+// field of vars reading it does, including its default and empty value options. Variables
+// without a field count as unset if empty.
+func resolve(name string, vars map[string]*fieldPlan) (string, bool) {
+	if f, ok := vars[name]; ok {
+		value, source := f.lookup()
+		return value, source != SourceUnset
+	}
+	value, ok := os.LookupEnv(name)
+	return value, ok && value != ""
+}
+
+// requiredBy returns the first condition making f required, if any
+func (f *fieldPlan) requiredBy(vars map[string]*fieldPlan) *Condition {
+	for i := range f.conditions {
+		if f.conditions[i].requires(resolve(f.conditions[i].Var, vars)) {
+			return &f.conditions[i]
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@
 package env
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -10,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -19,40 +22,44 @@ const (
 
 	constAliasTypeByte = "byte"
 	constAliasTypeRune = "rune"
+
+	constFormatJSON = "json"
 )
 
-// slices of supported types
 var (
-	sliceInt      = reflect.TypeOf([]int(nil))
-	sliceInt8     = reflect.TypeOf([]int8(nil))
-	sliceInt16    = reflect.TypeOf([]int16(nil))
-	sliceInt32    = reflect.TypeOf([]int32(nil))
-	sliceInt64    = reflect.TypeOf([]int64(nil))
-	sliceUint     = reflect.TypeOf([]uint(nil))
-	sliceUint8    = reflect.TypeOf([]uint8(nil))
-	sliceUint16   = reflect.TypeOf([]uint16(nil))
-	sliceUint32   = reflect.TypeOf([]uint32(nil))
-	sliceUint64   = reflect.TypeOf([]uint64(nil))
-	sliceString   = reflect.TypeOf([]string(nil))
-	sliceBool     = reflect.TypeOf([]bool(nil))
-	sliceFloat32  = reflect.TypeOf([]float32(nil))
-	sliceFloat64  = reflect.TypeOf([]float64(nil))
-	sliceDuration = reflect.TypeOf([]time.Duration(nil))
-	sliceTime     = reflect.TypeOf([]time.Time(nil))
-	sliceRegexp   = reflect.TypeOf([]*regexp.Regexp(nil))
-
-	// internal aliases, so not strictly necessary. Useful for documentational purposes.
-	sliceByte = reflect.TypeOf([]byte(nil))
-	sliceRune = reflect.TypeOf([]rune(nil))
+	typeFileMode = reflect.TypeOf(os.FileMode(0))
+
+	errUnrecognizedType      = errors.New("Unrecognized type")
+	errUnrecognizedSliceType = errors.New("Unrecognized slice type")
+	errEmptyValue            = errors.New("value must not be empty")
 )
 
 // Parse parses the environment values to the specified struct based on the struct tags
 //
 // Possible tag options are:
 // 	required       - the field must have a non-zero value
+// 	secret         - the value is sensitive, errors don't show it and templates leave it out
+// 	allowempty     - a set but empty variable counts as set, resetting the field to its zero value
+// 	notempty       - a set but empty variable is an error
+// 	required_if=VAR:a|b     - the field is required if VAR has one of the listed values
+// 	required_unless=VAR:a|b - the field is required unless VAR has one of the listed values
+// 	required_with=VAR|VAR2  - the field is required if any of the listed variables is set
+// 	group=name     - the field belongs to a group of variables, checked by the options below
+// 	exclusive      - at most one variable of the group may be set
+// 	atleastone     - at least one variable of the group must be set
 // 	default=Y      - the default value to use if variable is unset in environment
 // 	separator=X    - separator for multivalue environment values
+// 	separators=X|Y - separators for nested slices, outermost level first
 // 	type=byte|rune - type of value for values which reflect cannot distinguish between itself
+// 	oneof=a|b|c    - the value, or each value of a slice, must be one of the listed values
+// 	format=json    - the value is decoded as JSON, allowing structs, maps and nested values
+// 	quoted         - values may be double quoted or contain backslash escaped separators
+// 	trim           - whitespace around values, or each value of a slice, is removed
+// 	min=N, max=N   - lower and upper bound of numbers and durations, or length of strings
+// 	len=N          - exact length of strings
+// 	pattern=RE     - regular expression strings must match
+// 	minitems=N     - minimum number of values of a slice
+// 	maxitems=N     - maximum number of values of a slice
 //
 // Example usage:
 //  type Config struct {
@@ -65,339 +72,412 @@ var (
 //  config := &Config{}
 //  env.Parse(&config)
 //
+// Option values containing commas, such as a default for a slice, can be wrapped in single
+// quotes: `env:"NAMES,quoted,default='a,\"b,c\"'"` defaults to "a" and "b,c".
+//
+// Named types may restrict their allowed values by implementing a Values method
+// returning a slice of the type itself, e.g. func (Level) Values() []Level.
+// Such values are validated after conversion in addition to any oneof option.
+//
+// Values of type=rune fields are UTF-8 aware and may also be given as escape sequences
+// (\t, \u00e9) or code points (U+2192), for []rune every character is decoded this way.
+// A type=byte value is a single character or an escape sequence such as \t or \xff.
+//
+// Fields of type Secret hold a value parsed like any other field, which is redacted when
+// printed, encoded as JSON or logged. They are treated as if tagged with the secret option.
+//
+// Unset variables without a default leave their field untouched. Empty variables are treated
+// as unset, so the default applies and required fails, unless allowempty or notempty is given.
+//
+// Variables referenced by required_if, required_unless and required_with are read like the
+// field reading the same variable does, including its default, allowempty and notempty. The same
+// applies to the variables of groups, which may span nested structs. A group is exclusive
+// or requires at least one variable if any of its fields declares so.
+//
+// For slices min, max, len and pattern apply to each value, so `env:"HOSTS,min=1"` rejects
+// empty values. Constraints are only checked for set or defaulted variables.
+//
+// If the struct, or any nested struct, implements Validate() error it is called once its
+// fields are parsed. Errors of nested structs are prefixed with their field path, e.g.
+// "Server.TLS: certificate and key must both be set".
+//
+// All fields are parsed before an error is returned. Failures are returned as Errors holding
+// a *ParseError per field, which can be inspected with errors.As and matched against
+// ErrRequired, ErrInvalidValue, ErrUnsupportedType and ErrInvalidTag with errors.Is.
+//
 // See env_test.go for complete examples.
 func Parse(v interface{}) error {
+	return parseStruct(v, nil)
+}
+
+// parseStruct parses the struct v points to, appending the Explanation of every field to
+// explanations unless nil
+func parseStruct(v interface{}, explanations *[]Explanation) error {
+	elem, plan, err := structPlanOf(v)
+	if err != nil {
+		return err
+	}
+	errs := plan.parse(elem, plan.vars, "", explanations)
+	errs = append(errs, checkGroups(plan.groups, plan.vars)...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// structPlanOf returns the struct v points to along with its plan
+func structPlanOf(v interface{}) (reflect.Value, *structPlan, error) {
 	ptr := reflect.ValueOf(v)
 	if ptr.Kind() != reflect.Ptr {
-		return errors.New("Expected a pointer value")
+		return reflect.Value{}, nil, errors.New("Expected a pointer value")
 	}
 
 	elem := ptr.Elem()
 	if elem.Kind() != reflect.Struct {
-		return errors.New("Expected a struct pointer")
+		return reflect.Value{}, nil, errors.New("Expected a struct pointer")
 	}
 
-	return parseEnv(elem)
+	plan, err := planFor(elem.Type())
+	return elem, plan, err
 }
 
-func parseEnv(s reflect.Value) error {
-	sType := s.Type()
-	fieldCount := sType.NumField()
-	for i := 0; i < fieldCount; i++ {
-		field := s.Field(i)
-		fieldType := sType.Field(i)
-		tagValue := fieldType.Tag.Get("env")
-
-		if tagValue == "" {
-			// if the field is a nested struct, parse it and continue to next field
-			if field.Kind() == reflect.Struct {
-				if err := parseEnv(field); err != nil {
-					return err
-				}
+// parse sets the fields of s, a value of the planned struct type at path, from the
+// environment. Every field is parsed, Validate is only called if all of them succeeded.
+// Conditions of required_if, required_unless and required_with resolve variables like vars,
+// the fields of the parsed struct, read them. Fields are explained to explanations unless nil.
+func (p *structPlan) parse(s reflect.Value, vars map[string]*fieldPlan, path string, explanations *[]Explanation) Errors {
+	var errs Errors
+	for i := range p.fields {
+		f := &p.fields[i]
+		field := s.Field(f.index)
+		fieldPath := f.fieldName
+		if path != "" {
+			fieldPath = path + "." + f.fieldName
+		}
+
+		// if the field is a nested struct, parse it and continue to next field
+		if f.nested != nil {
+			nestedErrs := f.nested.parse(field, vars, fieldPath, explanations)
+			for _, err := range nestedErrs {
+				err.prefix(f.fieldName)
 			}
+			errs = append(errs, nestedErrs...)
 			continue
 		}
 
-		tags := strings.Split(tagValue, ",")
-		if len(tags) < 1 || len(tags[0]) == 0 {
-			return errors.New("env variable name cannot be empty")
+		value, source := f.lookup()
+		if err := f.parseValue(field, value, source, vars); err != nil {
+			errs = append(errs, err)
+		}
+		if explanations != nil {
+			*explanations = append(*explanations, f.explain(field, fieldPath, value, source))
 		}
+	}
 
-		envVariableName := tags[0]
-		value := os.Getenv(envVariableName)
+	if p.validate && len(errs) == 0 {
+		if err := s.Addr().Interface().(validator).Validate(); err != nil {
+			errs = append(errs, &ParseError{Err: err, kind: ErrInvalidValue})
+		}
+	}
+	return errs
+}
 
-		// parse environment based on tags
-		separator := DefaultSeparator
-		aliasType := ""
-		for _, tagValue := range tags[1:] {
-			if tagValue == "required" {
-				if value == "" {
-					return asParseError(envVariableName, "value is required but was empty")
-				}
-			} else if value == "" && strings.HasPrefix(tagValue, "default") {
-				value = namedOptionValue(tagValue)
-			} else if strings.HasPrefix(tagValue, "type") { // type allows override for go native aliases (byte,rune)
-				aliasType = namedOptionValue(tagValue)
+// parseValue sets field from value, the variable of f looked up from source, and checks its
+// constraints. Unset variables without a default leave the field untouched, as do empty ones
+// unless allowed with allowempty.
+func (f *fieldPlan) parseValue(field reflect.Value, value string, source Source, vars map[string]*fieldPlan) *ParseError {
+	if f.holder {
+		field = secretValueOf(field)
+	}
 
-				if aliasType != constAliasTypeRune && aliasType != constAliasTypeByte {
-					return asParseError(envVariableName, fmt.Sprintf("invalid type \"%s\", valid options are: \"%s\", \"%s\"", tagValue, constAliasTypeByte, constAliasTypeRune))
-				}
-			} else if strings.HasPrefix(tagValue, "separator") {
-				if tmp := namedOptionValue(tagValue); tmp != "" {
-					separator = tmp
-				}
-			} else {
-				return asParseError(envVariableName, fmt.Sprintf("unknown option %s", tagValue))
-			}
+	if source == SourceEnv && value == "" && f.notEmpty {
+		return f.newError(value, optionError("notempty", errEmptyValue), ErrInvalidValue)
+	}
+	ok := source != SourceUnset
+	if !ok && f.required {
+		return f.newError(value, optionError("required", ErrRequired), ErrRequired)
+	}
+	if !ok && f.conditions != nil {
+		if c := f.requiredBy(vars); c != nil {
+			return f.newError(value, optionError(c.Option, c.error()), ErrRequired)
 		}
+	}
+	if !ok {
+		return nil
+	}
+	if value == "" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	return f.setValue(field, value)
+}
 
-		// parse value to correct type and set it to field
-		switch field.Kind() {
-		default:
-			if err := parseSingle(fieldType, field, envVariableName, value, aliasType); err != nil {
-				return err
-			}
+// setValue parses value into field, the value of f or held by its Secret, and checks its
+// constraints
+func (f *fieldPlan) setValue(field reflect.Value, value string) *ParseError {
+	// parse value to correct type and set it to field
+	if err := f.parse(field, value); err != nil {
+		return f.newError(value, err, ErrInvalidValue)
+	}
 
-		case reflect.Slice:
-			if err := parseSlice(fieldType, field, envVariableName, value, separator, aliasType); err != nil {
-				return err
-			}
+	if f.allowedValues {
+		if err := checkAllowedValues(field); err != nil {
+			return f.newError(value, err, ErrInvalidValue)
 		}
 	}
 
+	if f.check != nil {
+		if err := f.check(field); err != nil {
+			return f.newError(value, err, ErrInvalidValue)
+		}
+	}
 	return nil
 }
 
-func parseSlice(fieldType reflect.StructField, field reflect.Value, envVariableName, value, separator, aliasType string) error {
-	data := strings.Split(value, separator)
+// newError completes err, which may be an option error, with the variable and value of f
+func (f *fieldPlan) newError(value string, err error, kind error) *ParseError {
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		parseErr = &ParseError{Err: err}
+	}
+	parseErr.Var, parseErr.FieldPath, parseErr.Value, parseErr.kind = f.name, f.fieldName, redact(value), kind
+	if f.secret && kind == ErrInvalidValue {
+		parseErr.Err = &secretError{err: parseErr.Err}
+	}
+	return parseErr
+}
 
-	switch field.Type() {
-	case sliceUint:
-		parsed := make([]uint, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseUint(d, 10, 32)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
-			}
-			parsed[idx] = uint(p)
+// valueOptions holds the tag options controlling how a value is split and converted
+type valueOptions struct {
+	separators []string
+	aliasType  string
+	format     string
+	oneOf      []string
+	quoted     bool
+	trim       bool
+}
+
+// parser converts value to the type of field and sets it
+type parser func(field reflect.Value, value string) error
+
+// newParser returns the parser for fields of type t with the given options
+func newParser(t reflect.Type, opts valueOptions) (parser, error) {
+	switch {
+	case opts.format == constFormatJSON:
+		return parseJSON, nil
+	case t.Kind() == reflect.Slice:
+		return newSliceParser(t, opts)
+	}
+
+	scalar, err := newScalarParser(t, opts.aliasType)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.quoted && !opts.trim && opts.oneOf == nil {
+		return scalar, nil
+	}
+	return func(field reflect.Value, value string) error {
+		data, err := splitValue(value, "", opts.quoted, true, opts.trim)
+		if err != nil {
+			return err
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceUint8, sliceByte:
-		if aliasType == constAliasTypeByte {
-			if len(data) > 1 {
-				return asParseError(envVariableName, "byte slice cannot have multiple values")
+		if value != "" && opts.oneOf != nil {
+			if err := checkOneOf(data[0], opts.oneOf); err != nil {
+				return err
 			}
-			field.Set(reflect.ValueOf([]byte(data[0])))
+		}
+		return scalar(field, data[0])
+	}, nil
+}
+
+// newSliceParser returns a parser splitting values on the first of separators and parsing
+// each element. Nested slices are parsed recursively using the remaining separators, levels
+// without a separator of their own fall back to DefaultSeparator.
+func newSliceParser(t reflect.Type, opts valueOptions) (parser, error) {
+	separator := DefaultSeparator
+	if len(opts.separators) > 0 && opts.separators[0] != "" {
+		separator = opts.separators[0]
+	}
+	nested := t.Elem().Kind() == reflect.Slice
+
+	var (
+		elem   parser
+		single bool
+		err    error
+	)
+	switch elemKind := t.Elem().Kind(); {
+	case nested:
+		inner := opts
+		inner.separators = nil
+		if len(opts.separators) > 1 {
+			inner.separators = opts.separators[1:]
+		}
+		if elem, err = newSliceParser(t.Elem(), inner); err != nil {
+			return nil, err
+		}
+
+	// byte and rune slices hold a single value when aliased
+	case elemKind == reflect.Uint8 && opts.aliasType == constAliasTypeByte:
+		single, elem = true, func(field reflect.Value, value string) error {
+			field.SetBytes([]byte(value))
 			return nil
 		}
-		parsed := make([]uint8, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseUint(d, 10, 8)
+	case elemKind == reflect.Int32 && opts.aliasType == constAliasTypeRune:
+		single, elem = true, func(field reflect.Value, value string) error {
+			runes, err := parseRunes(value)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
+				return err
 			}
-			parsed[idx] = uint8(p)
+			field.Set(reflect.ValueOf(runes).Convert(field.Type()))
+			return nil
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceUint16:
-		parsed := make([]uint16, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseUint(d, 10, 16)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
+
+	default:
+		if elem, err = newScalarParser(t.Elem(), opts.aliasType); err != nil {
+			if err == errUnrecognizedType {
+				return nil, errUnrecognizedSliceType
 			}
-			parsed[idx] = uint16(p)
+			return nil, err
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceUint32:
-		parsed := make([]uint32, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseUint(d, 10, 32)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
-			}
-			parsed[idx] = uint32(p)
+	}
+
+	return func(field reflect.Value, value string) error {
+		// quotes and escapes are kept until the innermost level has been split
+		data, err := splitValue(value, separator, opts.quoted, !nested, opts.trim)
+		if err != nil {
+			return err
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceUint64:
-		parsed := make([]uint64, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseUint(d, 10, 64)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
+
+		if !nested && opts.oneOf != nil {
+			for _, d := range data {
+				if err := checkOneOf(d, opts.oneOf); err != nil {
+					return err
+				}
 			}
-			parsed[idx] = uint64(p)
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceInt:
-		parsed := make([]int, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseInt(d, 10, 32)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
+
+		if single {
+			if len(data) > 1 {
+				return fmt.Errorf("%s slice cannot have multiple values", opts.aliasType)
 			}
-			parsed[idx] = int(p)
+			return elem(field, data[0])
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceInt8:
-		parsed := make([]int8, len(data))
+
+		parsed := reflect.MakeSlice(t, len(data), len(data))
 		for idx, d := range data {
-			p, err := strconv.ParseInt(d, 10, 8)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
+			if err := elem(parsed.Index(idx), d); err != nil {
+				return err
 			}
-			parsed[idx] = int8(p)
 		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceInt16:
-		parsed := make([]int16, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseInt(d, 10, 16)
+		field.Set(parsed)
+		return nil
+	}, nil
+}
+
+// parseJSON decodes value as JSON into a new value of the type of field, so maps and structs
+// it already holds are replaced rather than merged with
+func parseJSON(field reflect.Value, value string) error {
+	decoded := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), decoded.Interface()); err != nil {
+		return err
+	}
+	field.Set(decoded.Elem())
+	return nil
+}
+
+// newScalarParser returns the parser for t, which may be any scalar type supported
+// by Parse or a named type with one of their kinds.
+func newScalarParser(t reflect.Type, aliasType string) (parser, error) {
+	switch {
+	case t.String() == "time.Duration":
+		return func(field reflect.Value, value string) error {
+			v, err := time.ParseDuration(value)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
-			}
-			parsed[idx] = int16(p)
-		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceInt32, sliceRune:
-		if aliasType == constAliasTypeRune {
-			if len(data) > 1 {
-				return asParseError(envVariableName, "rune slice cannot have multiple values")
+				return err
 			}
-			field.Set(reflect.ValueOf([]rune(data[0])))
+			field.SetInt(int64(v))
 			return nil
-		}
-		parsed := make([]int32, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseInt(d, 10, 32)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
-			}
-			parsed[idx] = int32(p)
-		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceDuration:
-		parsed := make([]time.Duration, len(data))
-		for idx, d := range data {
-			v, err := time.ParseDuration(d)
+		}, nil
+
+	case t.String() == "time.Time":
+		return func(field reflect.Value, value string) error {
+			v, err := time.ParseInLocation(time.RFC3339, value, time.Local)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
+				return err
 			}
-			parsed[idx] = v
-		}
-		field.Set(reflect.ValueOf(parsed))
-		return nil
-	case sliceTime:
-		parsed := make([]time.Time, len(data))
-		for idx, d := range data {
-			v, err := time.ParseInLocation(time.RFC3339, d, time.Local)
+			field.Set(reflect.ValueOf(v))
+			return nil
+		}, nil
+
+	case t.String() == "*regexp.Regexp":
+		return func(field reflect.Value, value string) error {
+			v, err := regexp.Compile(value)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
+				return err
 			}
-			parsed[idx] = v
-		}
-		field.Set(reflect.ValueOf(parsed))
-		return nil
-	case sliceRegexp:
-		parsed := make([]*regexp.Regexp, len(data))
-		for idx, d := range data {
-			v, err := regexp.Compile(d)
+			field.Set(reflect.ValueOf(v))
+			return nil
+		}, nil
+
+	case t == typeFileMode:
+		return func(field reflect.Value, value string) error {
+			v, err := ParseFileMode(value)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
+				return err
 			}
-			parsed[idx] = v
-		}
-		field.Set(reflect.ValueOf(parsed))
-		return nil
-	case sliceInt64:
-		parsed := make([]int64, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseInt(d, 10, 64)
+			field.SetUint(uint64(v))
+			return nil
+		}, nil
+
+	// handle byte
+	case t.Kind() == reflect.Uint8 && aliasType == constAliasTypeByte:
+		return func(field reflect.Value, value string) error {
+			b, err := parseByte(value)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
+				return err
 			}
-			parsed[idx] = int64(p)
-		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceString:
-		field.Set(reflect.ValueOf(data))
-	case sliceBool:
-		parsed := make([]bool, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseBool(d)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
+			field.SetUint(uint64(b))
+			return nil
+		}, nil
+
+	// handle rune
+	case t.Kind() == reflect.Int32 && aliasType == constAliasTypeRune:
+		return func(field reflect.Value, value string) error {
+			if value == "" {
+				return errors.New("rune must be a single character value")
 			}
-			parsed[idx] = bool(p)
-		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceFloat32:
-		parsed := make([]float32, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseFloat(d, 32)
+			r, tail, err := unquoteRune(value)
 			if err != nil {
-				return asParseError(envVariableName, err.Error())
+				return err
 			}
-			parsed[idx] = float32(p)
-		}
-		field.Set(reflect.ValueOf(parsed))
-	case sliceFloat64:
-		parsed := make([]float64, len(data))
-		for idx, d := range data {
-			p, err := strconv.ParseFloat(d, 64)
-			if err != nil {
-				return asParseError(envVariableName, err.Error())
+			if tail != "" {
+				return errors.New("rune must be a single character value")
 			}
-			parsed[idx] = float64(p)
-		}
-		field.Set(reflect.ValueOf(parsed))
-
-	default:
-		return errors.New("Unrecognized slice type")
-	}
-	return nil
-}
-
-func parseSingle(fieldType reflect.StructField, field reflect.Value, envVariableName, value, aliasType string) error {
-	if fieldType.Type.String() == "time.Duration" {
-		v, err := time.ParseDuration(value)
-		if err != nil {
-			return asParseError(envVariableName, err.Error())
-		}
-		field.Set(reflect.ValueOf(v))
-		return nil
-	}
-
-	if fieldType.Type.String() == "time.Time" {
-		v, err := time.ParseInLocation(time.RFC3339, value, time.Local)
-		if err != nil {
-			return asParseError(envVariableName, err.Error())
-		}
-		field.Set(reflect.ValueOf(v))
-		return nil
-	}
-
-	if fieldType.Type.String() == "*regexp.Regexp" {
-		v, err := regexp.Compile(value)
-		if err != nil {
-			return asParseError(envVariableName, err.Error())
-		}
-		field.Set(reflect.ValueOf(v))
-		return nil
-	}
-
-	// handle byte
-	if field.Kind() == reflect.Uint8 && aliasType == constAliasTypeByte {
-		if len(value) != 1 {
-			return asParseError(envVariableName, "byte must be a single character value")
-		}
-		field.Set(reflect.ValueOf(byte(value[0])))
-		return nil
-	}
-
-	// handle rune
-	if field.Kind() == reflect.Int32 && aliasType == constAliasTypeRune {
-		if len(value) != 1 {
-			return asParseError(envVariableName, "rune must be a single character value")
-		}
-		field.Set(reflect.ValueOf(rune(value[0])))
-		return nil
+			field.SetInt(int64(r))
+			return nil
+		}, nil
 	}
 
-	switch field.Kind() {
+	switch t.Kind() {
 	case reflect.String:
-		field.SetString(value)
+		return func(field reflect.Value, value string) error {
+			field.SetString(value)
+			return nil
+		}, nil
 
 	case reflect.Bool:
-		bvalue, err := strconv.ParseBool(value)
-		if err != nil {
-			return asParseError(envVariableName, err.Error())
-		}
-		field.SetBool(bvalue)
+		return func(field reflect.Value, value string) error {
+			bvalue, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			field.SetBool(bvalue)
+			return nil
+		}, nil
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		var bitSize int
-		switch field.Kind() {
+		switch t.Kind() {
 		case reflect.Uint8:
 			bitSize = 8
 		case reflect.Uint16:
@@ -407,15 +487,18 @@ func parseSingle(fieldType reflect.StructField, field reflect.Value, envVariable
 		case reflect.Uint64:
 			bitSize = 64
 		}
-		uintValue, err := strconv.ParseUint(value, 10, bitSize)
-		if err != nil {
-			return asParseError(envVariableName, err.Error())
-		}
-		field.SetUint(uintValue)
+		return func(field reflect.Value, value string) error {
+			uintValue, err := strconv.ParseUint(value, 10, bitSize)
+			if err != nil {
+				return err
+			}
+			field.SetUint(uintValue)
+			return nil
+		}, nil
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		var bitSize int
-		switch field.Kind() {
+		switch t.Kind() {
 		case reflect.Int8:
 			bitSize = 8
 		case reflect.Int16:
@@ -425,40 +508,355 @@ func parseSingle(fieldType reflect.StructField, field reflect.Value, envVariable
 		case reflect.Int64:
 			bitSize = 64
 		}
-		intValue, err := strconv.ParseInt(value, 10, bitSize)
-		if err != nil {
-			return asParseError(envVariableName, err.Error())
-		}
-		field.SetInt(intValue)
+		return func(field reflect.Value, value string) error {
+			intValue, err := strconv.ParseInt(value, 10, bitSize)
+			if err != nil {
+				return err
+			}
+			field.SetInt(intValue)
+			return nil
+		}, nil
 
 	case reflect.Float32, reflect.Float64:
 		var bitSize int
-		switch field.Kind() {
+		switch t.Kind() {
 		case reflect.Float32:
 			bitSize = 32
 		case reflect.Float64:
 			bitSize = 64
 		}
-		v, err := strconv.ParseFloat(value, bitSize)
+		return func(field reflect.Value, value string) error {
+			v, err := strconv.ParseFloat(value, bitSize)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(v)
+			return nil
+		}, nil
+	}
+
+	return nil, errUnrecognizedType
+}
+
+// checkOneOf validates a raw value against the values listed in a oneof option
+func checkOneOf(value string, oneOf []string) error {
+	for _, allowed := range oneOf {
+		if value == allowed {
+			return nil
+		}
+	}
+	return optionError("oneof", fmt.Errorf("invalid value \"%s\", valid options are: \"%s\"", value, strings.Join(oneOf, "\", \"")))
+}
+
+// checkAllowedValues validates a converted value, or each value of a slice, against the
+// values returned by the Values method of its type. Types without one are not restricted.
+func checkAllowedValues(v reflect.Value) error {
+	allowed, ok := allowedValues(v.Type())
+	if !ok {
+		if v.Kind() == reflect.Slice && hasAllowedValues(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				if err := checkAllowedValues(v.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	options := make([]string, allowed.Len())
+	for i := 0; i < allowed.Len(); i++ {
+		if reflect.DeepEqual(allowed.Index(i).Interface(), v.Interface()) {
+			return nil
+		}
+		options[i] = fmt.Sprint(allowed.Index(i).Interface())
+	}
+	return fmt.Errorf("invalid value \"%v\", valid options are: \"%s\"", v.Interface(), strings.Join(options, "\", \""))
+}
+
+// allowedValues calls the Values method of t, if t has one returning []t
+func allowedValues(t reflect.Type) (reflect.Value, bool) {
+	method, ok := t.MethodByName("Values")
+	if !ok || method.Type.NumIn() != 1 || method.Type.NumOut() != 1 || method.Type.Out(0) != reflect.SliceOf(t) {
+		return reflect.Value{}, false
+	}
+	return method.Func.Call([]reflect.Value{reflect.Zero(t)})[0], true
+}
+
+// hasAllowedValues reports whether t, or the element type of a (nested) slice t, has a Values method
+func hasAllowedValues(t reflect.Type) bool {
+	if _, ok := allowedValues(t); ok {
+		return true
+	}
+	return t.Kind() == reflect.Slice && hasAllowedValues(t.Elem())
+}
+
+// ParseFileMode parses permissions in octal (0640, 0o640) or symbolic (rw-r-----) notation
+// like Parse does for os.FileMode fields. Octal values are always read as octal, with or
+// without a leading zero, like chmod does.
+func ParseFileMode(value string) (os.FileMode, error) {
+	symbolic := value
+	if len(symbolic) == 10 && symbolic[0] == '-' {
+		symbolic = symbolic[1:]
+	}
+	if len(symbolic) == 9 && strings.IndexFunc(symbolic, isDigit) < 0 {
+		return parseSymbolicFileMode(symbolic, value)
+	}
+
+	octal := value
+	if strings.HasPrefix(octal, "0o") || strings.HasPrefix(octal, "0O") {
+		octal = octal[2:]
+	}
+	perm, err := strconv.ParseUint(octal, 8, 32)
+	if err != nil || perm > 07777 {
+		return 0, fmt.Errorf("invalid file mode \"%s\"", value)
+	}
+
+	mode := os.FileMode(perm & 0777)
+	if perm&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if perm&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if perm&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// parseSymbolicFileMode parses the nine permission characters of ls -l output, including
+// the s, S, t and T forms of the setuid, setgid and sticky bits
+func parseSymbolicFileMode(symbolic, value string) (os.FileMode, error) {
+	specials := [9]os.FileMode{2: os.ModeSetuid, 5: os.ModeSetgid, 8: os.ModeSticky}
+
+	var mode os.FileMode
+	for idx := 0; idx < len(symbolic); idx++ {
+		bit := os.FileMode(1) << uint(8-idx)
+		switch c := symbolic[idx]; {
+		case c == '-':
+		case c == "rwxrwxrwx"[idx]:
+			mode |= bit
+		case specials[idx] != 0 && c == "--s--s--t"[idx]: // special bit with execute
+			mode |= specials[idx] | bit
+		case specials[idx] != 0 && c == "--S--S--T"[idx]: // special bit without execute
+			mode |= specials[idx]
+		default:
+			return 0, fmt.Errorf("invalid file mode \"%s\"", value)
+		}
+	}
+	return mode, nil
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+// parseByte parses a single character or an escape sequence such as \t or \xff as a byte
+func parseByte(value string) (byte, error) {
+	if len(value) == 1 {
+		return value[0], nil
+	}
+	if strings.HasPrefix(value, "\\") {
+		r, multibyte, tail, err := strconv.UnquoteChar(value, '\'')
+		if err == nil && !multibyte && tail == "" {
+			return byte(r), nil
+		}
+	}
+	return 0, errors.New("byte must be a single character value")
+}
+
+// parseRunes decodes value to runes, accepting the same forms as unquoteRune for each rune
+func parseRunes(value string) ([]rune, error) {
+	runes := make([]rune, 0, len(value))
+	for value != "" {
+		r, tail, err := unquoteRune(value)
 		if err != nil {
-			return asParseError(envVariableName, err.Error())
+			return nil, err
 		}
-		field.SetFloat(v)
+		runes = append(runes, r)
+		value = tail
+	}
+	return runes, nil
+}
 
-	default:
-		return errors.New("Unrecognized type")
+// unquoteRune decodes the first rune of value, which may be an UTF-8 encoded character,
+// an escape sequence such as \t or \u00e9, or a Unicode code point in U+2192 notation.
+// The remainder of value is returned as tail.
+func unquoteRune(value string) (r rune, tail string, err error) {
+	if strings.HasPrefix(value, "U+") {
+		digits := 2
+		for digits < len(value) && digits < 8 && isHexDigit(value[digits]) {
+			digits++
+		}
+		if digits < 6 {
+			return 0, "", fmt.Errorf("invalid code point \"%s\"", value)
+		}
+		code, _ := strconv.ParseUint(value[2:digits], 16, 32)
+		if !utf8.ValidRune(rune(code)) {
+			return 0, "", fmt.Errorf("invalid code point \"%s\"", value[:digits])
+		}
+		return rune(code), value[digits:], nil
 	}
-	return nil
+
+	if strings.HasPrefix(value, "\\") {
+		r, _, tail, err := strconv.UnquoteChar(value, '\'')
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid escape sequence in \"%s\"", value)
+		}
+		return r, tail, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(value)
+	if r == utf8.RuneError && size <= 1 {
+		return 0, "", errors.New("invalid UTF-8 encoding")
+	}
+	return r, value[size:], nil
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
 }
 
 func asParseError(envVariableName, err string) error {
 	return fmt.Errorf("%s: %s", envVariableName, err)
 }
 
+// splitTag splits an env tag into its comma separated options. An option value
+// wrapped in single quotes, e.g. default='a,b', may contain commas. Otherwise an
+// empty option directly following separator= or separators= is read as a
+// literal comma belonging to that option, so "separators=;|," splits nested
+// values on ";" and then ",".
+func splitTag(tag string) []string {
+	var options []string
+	for more := true; more; {
+		end := strings.IndexByte(tag, ',')
+		if eq := strings.IndexByte(tag, '='); eq >= 0 && (end < 0 || eq < end) && strings.HasPrefix(tag[eq+1:], "'") {
+			if closing := closingQuote(tag[eq+2:]); closing >= 0 {
+				end = eq + 2 + closing + 1
+				if end == len(tag) {
+					end = -1
+				}
+			}
+		}
+
+		option := tag
+		if more = end >= 0; more {
+			option, tag = tag[:end], tag[end+1:]
+		}
+
+		if last := len(options) - 1; option == "" && last > 0 && strings.HasPrefix(options[last], "separator") {
+			options[last] += ","
+			continue
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// closingQuote returns the index of the first single quote in s which ends an option value
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' && (i+1 == len(s) || s[i+1] == ',') {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitValue splits value on separator, an empty separator returns value as is.
+// In quoted mode separators within double quotes or escaped by a backslash are
+// not split on and unquote controls whether quotes and escapes are removed or
+// kept for the next level of a nested slice. With trim, whitespace around the
+// elements is removed, though never from quoted or escaped characters.
+func splitValue(value, separator string, quoted, unquote, trim bool) ([]string, error) {
+	if !quoted {
+		data := []string{value}
+		if separator != "" {
+			data = strings.Split(value, separator)
+		}
+		if trim {
+			for idx, d := range data {
+				data[idx] = strings.TrimSpace(d)
+			}
+		}
+		return data, nil
+	}
+
+	var (
+		data       []string
+		elem       = make([]byte, 0, len(value))
+		start, end = -1, -1 // range of elem which may not be trimmed
+		inQuotes   bool
+	)
+	protect := func(b ...byte) {
+		if start < 0 {
+			start = len(elem)
+		}
+		elem = append(elem, b...)
+		end = len(elem)
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(value) {
+				return nil, errors.New("unterminated escape sequence")
+			}
+			i++
+			if unquote {
+				protect(value[i])
+			} else {
+				protect(c, value[i])
+			}
+		case c == '"' && inQuotes && i+1 < len(value) && value[i+1] == '"':
+			i++
+			if unquote {
+				protect(c)
+			} else {
+				protect(c, c)
+			}
+		case c == '"':
+			inQuotes = !inQuotes
+			if unquote {
+				protect()
+			} else {
+				protect(c)
+			}
+		case inQuotes:
+			protect(c)
+		case separator != "" && strings.HasPrefix(value[i:], separator):
+			data = append(data, trimElement(elem, start, end, trim))
+			elem, start, end = elem[:0], -1, -1
+			i += len(separator) - 1
+		default:
+			elem = append(elem, c)
+		}
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quoted value")
+	}
+	return append(data, trimElement(elem, start, end, trim)), nil
+}
+
+// trimElement converts elem to a string, trimming whitespace outside of elem[start:end] if trim is set
+func trimElement(elem []byte, start, end int, trim bool) string {
+	if !trim {
+		return string(elem)
+	}
+	if start < 0 {
+		return strings.TrimSpace(string(elem))
+	}
+	return strings.TrimLeftFunc(string(elem[:start]), unicode.IsSpace) + string(elem[start:end]) + strings.TrimRightFunc(string(elem[end:]), unicode.IsSpace)
+}
+
+// namedOptionValue returns the value of a name=value option, without any enclosing single quotes
 func namedOptionValue(val string) string {
 	split := strings.SplitN(val, "=", 2)
 	if len(split) != 2 {
 		return ""
 	}
+	if value := split[1]; len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
 	return split[1]
 }
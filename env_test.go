@@ -1,13 +1,17 @@
 package env_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"os"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -277,8 +281,8 @@ func TestParseStructWithMissingEnvVariableName(t *testing.T) {
 	}{}
 
 	withResetEnv(func() {
-		assert.Equal(errors.New("env variable name cannot be empty"), env.Parse(&testStruct))
-		assert.Equal(errors.New("env variable name cannot be empty"), env.Parse(&testNestedStruct))
+		assert.EqualError(env.Parse(&testStruct), "StringValue: env variable name cannot be empty")
+		assert.EqualError(env.Parse(&testNestedStruct), "StructValue.StringValue: env variable name cannot be empty")
 	})
 }
 
@@ -291,7 +295,7 @@ func TestParseStructUnknownOption(t *testing.T) {
 	}{}
 
 	withResetEnv(func() {
-		assert.Equal(errors.New("S1: unknown option abc"), env.Parse(&testStruct))
+		assert.EqualError(env.Parse(&testStruct), "S1: unknown option abc")
 	})
 }
 
@@ -313,12 +317,12 @@ func TestParseAliasType(t *testing.T) {
 		testInvalidStruct := struct {
 			Value interface{} `env:"S1,type=invalid"`
 		}{}
-		assert.Equal(errors.New("S1: invalid type \"type=invalid\", valid options are: \"byte\", \"rune\""), env.Parse(&testInvalidStruct))
+		assert.EqualError(env.Parse(&testInvalidStruct), "S1: invalid type \"type=invalid\", valid options are: \"byte\", \"rune\"")
 
 		testInvalidValueStruct := struct {
 			Value interface{} `env:"S1,type"`
 		}{}
-		assert.Equal(errors.New("S1: invalid type \"type\", valid options are: \"byte\", \"rune\""), env.Parse(&testInvalidValueStruct))
+		assert.EqualError(env.Parse(&testInvalidValueStruct), "S1: invalid type \"type\", valid options are: \"byte\", \"rune\"")
 	})
 }
 
@@ -383,7 +387,7 @@ func TestParseRequired(t *testing.T) {
 
 			err := env.Parse(testStruct)
 			assert.Error(err)
-			assert.Equal(errors.New(envKey+": value is required but was empty"), err)
+			assert.EqualError(err, envKey+": value is required but was empty")
 
 			os.Setenv(envKey, prev)
 		}
@@ -680,12 +684,12 @@ func TestParseInvalidEnvValues(t *testing.T) {
 			{
 				EnvKey:        "GO_ENV_TEST_TIME_DURATION_VALUE",
 				EnvValue:      "abc",
-				ExpectedError: errors.New("GO_ENV_TEST_TIME_DURATION_VALUE: time: invalid duration abc"),
+				ExpectedError: errors.New("GO_ENV_TEST_TIME_DURATION_VALUE: time: invalid duration \"abc\""),
 			},
 			{
 				EnvKey:        "GO_ENV_TEST_TIME_DURATION_SLICE_VALUE",
 				EnvValue:      "abc",
-				ExpectedError: errors.New("GO_ENV_TEST_TIME_DURATION_SLICE_VALUE: time: invalid duration abc"),
+				ExpectedError: errors.New("GO_ENV_TEST_TIME_DURATION_SLICE_VALUE: time: invalid duration \"abc\""),
 			},
 			{
 				EnvKey:        "GO_ENV_TEST_TIME_VALUE",
@@ -713,13 +717,1263 @@ func TestParseInvalidEnvValues(t *testing.T) {
 			os.Setenv(tc.EnvKey, tc.EnvValue)
 
 			err := env.Parse(testStruct)
-			assert.Equal(tc.ExpectedError, err)
+			assert.EqualError(err, tc.ExpectedError.Error())
 
 			os.Setenv(tc.EnvKey, prev)
 		}
 	})
 }
 
+func TestParseNestedSlices(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_ROUTES", "a,b;c;d,e,f")
+		os.Setenv("GO_ENV_TEST_SHARDS", "1,2;3,4")
+		os.Setenv("GO_ENV_TEST_SECRETS", "abc,def")
+
+		testStruct := struct {
+			Routes  [][]string `env:"GO_ENV_TEST_ROUTES,separators=;|,"`
+			Shards  [][]int    `env:"GO_ENV_TEST_SHARDS,separators=;"`
+			Secrets [][]byte   `env:"GO_ENV_TEST_SECRETS,type=byte"`
+			Default [][]uint8  `env:"GO_ENV_TEST_DEFAULT,separators=;|:,default=1:2;3"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal([][]string{{"a", "b"}, {"c"}, {"d", "e", "f"}}, testStruct.Routes)
+		assert.Equal([][]int{{1, 2}, {3, 4}}, testStruct.Shards)
+		assert.Equal([][]byte{[]byte("abc"), []byte("def")}, testStruct.Secrets)
+		assert.Equal([][]uint8{{1, 2}, {3}}, testStruct.Default)
+
+		invalidStruct := struct {
+			Shards [][]int `env:"GO_ENV_TEST_SHARDS,separators=;|:"`
+		}{}
+		assert.EqualError(env.Parse(&invalidStruct), "GO_ENV_TEST_SHARDS: strconv.ParseInt: parsing \"1,2\": invalid syntax")
+	})
+}
+
+func TestParseCommaSeparator(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_COMMA", "a,b")
+
+		testStruct := struct {
+			Values   []string `env:"GO_ENV_TEST_COMMA,separator=,"`
+			Required []string `env:"GO_ENV_TEST_COMMA,separator=,,required"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal([]string{"a", "b"}, testStruct.Values)
+		assert.Equal([]string{"a", "b"}, testStruct.Required)
+	})
+}
+
+type testLevel string
+
+func (testLevel) Values() []testLevel {
+	return []testLevel{"debug", "info", "warn"}
+}
+
+type testMode int
+
+func (testMode) Values() []testMode {
+	return []testMode{1, 2}
+}
+
+func TestParseOneOf(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_ENVIRONMENT", "staging")
+		os.Setenv("GO_ENV_TEST_ENVIRONMENTS", "dev,staging")
+		os.Setenv("GO_ENV_TEST_LEVEL", "info")
+		os.Setenv("GO_ENV_TEST_MODE", "2")
+
+		testStruct := struct {
+			Environment  string    `env:"GO_ENV_TEST_ENVIRONMENT,oneof=dev|staging|prod"`
+			Environments []string  `env:"GO_ENV_TEST_ENVIRONMENTS,oneof=dev|staging|prod"`
+			Level        testLevel `env:"GO_ENV_TEST_LEVEL"`
+			Mode         testMode  `env:"GO_ENV_TEST_MODE"`
+			Unset        testLevel `env:"GO_ENV_TEST_UNSET"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal("staging", testStruct.Environment)
+		assert.Equal([]string{"dev", "staging"}, testStruct.Environments)
+		assert.Equal(testLevel("info"), testStruct.Level)
+		assert.Equal(testMode(2), testStruct.Mode)
+
+		for _, tc := range []struct {
+			EnvKey        string
+			EnvValue      string
+			ExpectedError error
+		}{
+			{
+				EnvKey:        "GO_ENV_TEST_ENVIRONMENT",
+				EnvValue:      "prd",
+				ExpectedError: errors.New("GO_ENV_TEST_ENVIRONMENT: invalid value \"prd\", valid options are: \"dev\", \"staging\", \"prod\""),
+			},
+			{
+				EnvKey:        "GO_ENV_TEST_ENVIRONMENTS",
+				EnvValue:      "dev,stagign",
+				ExpectedError: errors.New("GO_ENV_TEST_ENVIRONMENTS: invalid value \"stagign\", valid options are: \"dev\", \"staging\", \"prod\""),
+			},
+			{
+				EnvKey:        "GO_ENV_TEST_LEVEL",
+				EnvValue:      "inf",
+				ExpectedError: errors.New("GO_ENV_TEST_LEVEL: invalid value \"inf\", valid options are: \"debug\", \"info\", \"warn\""),
+			},
+			{
+				EnvKey:        "GO_ENV_TEST_MODE",
+				EnvValue:      "3",
+				ExpectedError: errors.New("GO_ENV_TEST_MODE: invalid value \"3\", valid options are: \"1\", \"2\""),
+			},
+		} {
+			prev := os.Getenv(tc.EnvKey)
+			os.Setenv(tc.EnvKey, tc.EnvValue)
+
+			assert.EqualError(env.Parse(&testStruct), tc.ExpectedError.Error())
+
+			os.Setenv(tc.EnvKey, prev)
+		}
+	})
+}
+
+func TestParseUnicodeAliasType(t *testing.T) {
+	assert := require.New(t)
+
+	type runeStruct struct {
+		Value rune `env:"GO_ENV_TEST_RUNE,type=rune"`
+	}
+	type runeSliceStruct struct {
+		Value []rune `env:"GO_ENV_TEST_RUNE,type=rune"`
+	}
+	type byteStruct struct {
+		Value byte `env:"GO_ENV_TEST_BYTE,type=byte"`
+	}
+
+	withResetEnv(func() {
+		for value, expected := range map[string]rune{
+			"a":           'a',
+			"é":           'é',
+			"→":           '→',
+			"\\t":         '\t',
+			"\\u00e9":     'é',
+			"\\U0001F600": '😀',
+			"U+2192":      '→',
+			"U+1F600":     '😀',
+		} {
+			os.Setenv("GO_ENV_TEST_RUNE", value)
+			testStruct := runeStruct{}
+			assert.Nil(env.Parse(&testStruct), value)
+			assert.Equal(expected, testStruct.Value, value)
+		}
+
+		for value, expected := range map[string][]rune{
+			"é→;":        {'é', '→', ';'},
+			"\\t|U+2192": {'\t', '|', '→'},
+			"a\\\\b":     {'a', '\\', 'b'},
+		} {
+			os.Setenv("GO_ENV_TEST_RUNE", value)
+			testStruct := runeSliceStruct{}
+			assert.Nil(env.Parse(&testStruct), value)
+			assert.Equal(expected, testStruct.Value, value)
+		}
+
+		for value, expected := range map[string]byte{
+			";":     ';',
+			"\\t":   '\t',
+			"\\xff": 0xff,
+		} {
+			os.Setenv("GO_ENV_TEST_BYTE", value)
+			testStruct := byteStruct{}
+			assert.Nil(env.Parse(&testStruct), value)
+			assert.Equal(expected, testStruct.Value, value)
+		}
+
+		for value, expected := range map[string]error{
+			"éé":       errors.New("GO_ENV_TEST_RUNE: rune must be a single character value"),
+			"\xff":     errors.New("GO_ENV_TEST_RUNE: invalid UTF-8 encoding"),
+			"\\q":      errors.New("GO_ENV_TEST_RUNE: invalid escape sequence in \"\\q\""),
+			"U+21":     errors.New("GO_ENV_TEST_RUNE: invalid code point \"U+21\""),
+			"U+110000": errors.New("GO_ENV_TEST_RUNE: invalid code point \"U+110000\""),
+		} {
+			os.Setenv("GO_ENV_TEST_RUNE", value)
+			assert.EqualError(env.Parse(&runeStruct{}), expected.Error(), value)
+		}
+
+		for _, value := range []string{"é", "ab", "\\u00e9"} {
+			os.Setenv("GO_ENV_TEST_BYTE", value)
+			assert.EqualError(env.Parse(&byteStruct{}), "GO_ENV_TEST_BYTE: byte must be a single character value", value)
+		}
+	})
+}
+
+func TestParseQuotedSlices(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_PATTERNS", `^[a-z]{1\,3}$,"^(a|b),c$"`)
+		os.Setenv("GO_ENV_TEST_NAMES", ` Jane Doe , "Doe, John " ,  `)
+		os.Setenv("GO_ENV_TEST_GROUPS", `"a;b",c;d\,e`)
+		os.Setenv("GO_ENV_TEST_QUOTE", `"say ""hi"""`)
+		os.Setenv("GO_ENV_TEST_TRIMMED", " 1, 2 ,3 ")
+
+		testStruct := struct {
+			Patterns []*regexp.Regexp `env:"GO_ENV_TEST_PATTERNS,quoted"`
+			Names    []string         `env:"GO_ENV_TEST_NAMES,quoted,trim"`
+			Groups   [][]string       `env:"GO_ENV_TEST_GROUPS,quoted,separators=;"`
+			Quote    string           `env:"GO_ENV_TEST_QUOTE,quoted"`
+			Trimmed  []int            `env:"GO_ENV_TEST_TRIMMED,trim"`
+			Default  []string         `env:"GO_ENV_TEST_DEFAULT,quoted,default='a,\"b,c\"'"`
+			Escaped  []string         `env:"GO_ENV_TEST_ESCAPED,quoted,default='a\\,b,c',trim"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal([]*regexp.Regexp{regexp.MustCompile("^[a-z]{1,3}$"), regexp.MustCompile("^(a|b),c$")}, testStruct.Patterns)
+		assert.Equal([]string{"Jane Doe", "Doe, John ", ""}, testStruct.Names)
+		assert.Equal([][]string{{"a;b", "c"}, {"d,e"}}, testStruct.Groups)
+		assert.Equal(`say "hi"`, testStruct.Quote)
+		assert.Equal([]int{1, 2, 3}, testStruct.Trimmed)
+		assert.Equal([]string{"a", "b,c"}, testStruct.Default)
+		assert.Equal([]string{"a,b", "c"}, testStruct.Escaped)
+
+		os.Setenv("GO_ENV_TEST_NAMES", `"Doe, John`)
+		assert.EqualError(env.Parse(&testStruct), "GO_ENV_TEST_NAMES: unterminated quoted value")
+
+		os.Setenv("GO_ENV_TEST_NAMES", `Doe\`)
+		assert.EqualError(env.Parse(&testStruct), "GO_ENV_TEST_NAMES: unterminated escape sequence")
+	})
+}
+
+func TestParseDefaultWithValueSet(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_STRING_VALUE", "abc")
+
+		testStruct := struct {
+			StringValue string `env:"GO_ENV_TEST_STRING_VALUE,default=def"`
+			Unset       string `env:"GO_ENV_TEST_UNSET,default=' padded '"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal("abc", testStruct.StringValue)
+		assert.Equal(" padded ", testStruct.Unset)
+	})
+}
+
+func TestParseJSONFormat(t *testing.T) {
+	assert := require.New(t)
+
+	type retryPolicy struct {
+		Attempts int           `json:"attempts"`
+		Backoff  time.Duration `json:"backoff"`
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_RETRY", `{"attempts":3,"backoff":1000000000}`)
+		os.Setenv("GO_ENV_TEST_FLAGS", `{"beta":true,"dark-mode":false}`)
+		os.Setenv("GO_ENV_TEST_HOSTS", `["a,b","c"]`)
+
+		testStruct := struct {
+			Retry    retryPolicy     `env:"GO_ENV_TEST_RETRY,format=json"`
+			Flags    map[string]bool `env:"GO_ENV_TEST_FLAGS,format=json"`
+			Hosts    []string        `env:"GO_ENV_TEST_HOSTS,format=json"`
+			Policies []retryPolicy   `env:"GO_ENV_TEST_POLICIES,format=json,default='[{\"attempts\":1}]'"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal(retryPolicy{Attempts: 3, Backoff: time.Second}, testStruct.Retry)
+		assert.Equal(map[string]bool{"beta": true, "dark-mode": false}, testStruct.Flags)
+		assert.Equal([]string{"a,b", "c"}, testStruct.Hosts)
+		assert.Equal([]retryPolicy{{Attempts: 1}}, testStruct.Policies)
+
+		// values replace maps and structs held by the field instead of merging with them
+		os.Setenv("GO_ENV_TEST_RETRY", `{"backoff":2000000000}`)
+		os.Setenv("GO_ENV_TEST_FLAGS", `{"gamma":true}`)
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal(retryPolicy{Backoff: 2 * time.Second}, testStruct.Retry)
+		assert.Equal(map[string]bool{"gamma": true}, testStruct.Flags)
+
+		os.Setenv("GO_ENV_TEST_RETRY", `{"attempts":"3"}`)
+		assert.EqualError(env.Parse(&testStruct), "GO_ENV_TEST_RETRY: json: cannot unmarshal string into Go struct field retryPolicy.attempts of type int")
+
+		invalidStruct := struct {
+			Value map[string]string `env:"GO_ENV_TEST_RETRY,format=yaml"`
+		}{}
+		assert.EqualError(env.Parse(&invalidStruct), "GO_ENV_TEST_RETRY: invalid format \"format=yaml\", valid options are: \"json\"")
+	})
+}
+
+func TestParseFileMode(t *testing.T) {
+	assert := require.New(t)
+
+	type fileModeStruct struct {
+		Value os.FileMode `env:"GO_ENV_TEST_FILE_MODE"`
+	}
+
+	withResetEnv(func() {
+		for value, expected := range map[string]os.FileMode{
+			"0640":       0640,
+			"640":        0640,
+			"0o755":      0755,
+			"0":          0,
+			"4755":       os.ModeSetuid | 0755,
+			"1777":       os.ModeSticky | 0777,
+			"rw-r-----":  0640,
+			"-rwxr-xr-x": 0755,
+			"---------":  0,
+			"rwsr-Sr-t":  os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0745,
+		} {
+			os.Setenv("GO_ENV_TEST_FILE_MODE", value)
+			testStruct := fileModeStruct{}
+			assert.Nil(env.Parse(&testStruct), value)
+			assert.Equal(expected, testStruct.Value, value)
+		}
+
+		for _, value := range []string{"0648", "10000", "rw-r--r-x-", "rw-rw-rwz", "rwxrwxrws", "abc"} {
+			os.Setenv("GO_ENV_TEST_FILE_MODE", value)
+			assert.EqualError(env.Parse(&fileModeStruct{}), fmt.Sprintf("GO_ENV_TEST_FILE_MODE: invalid file mode \"%s\"", value), value)
+		}
+
+		os.Setenv("GO_ENV_TEST_FILE_MODE", "0600,rw-rw----")
+		testSliceStruct := struct {
+			Value []os.FileMode `env:"GO_ENV_TEST_FILE_MODE"`
+		}{}
+		assert.Nil(env.Parse(&testSliceStruct))
+		assert.Equal([]os.FileMode{0600, 0660}, testSliceStruct.Value)
+	})
+}
+
+func TestParseValidation(t *testing.T) {
+	assert := require.New(t)
+
+	type validationStruct struct {
+		Port    int           `env:"GO_ENV_TEST_PORT,min=1,max=65535"`
+		Ratio   float64       `env:"GO_ENV_TEST_RATIO,min=0,max=1"`
+		Timeout time.Duration `env:"GO_ENV_TEST_TIMEOUT,min=1s,max=1m"`
+		Token   string        `env:"GO_ENV_TEST_TOKEN,len=4"`
+		Name    string        `env:"GO_ENV_TEST_NAME,min=2,max=5,pattern='^[a-zé]+$'"`
+		Hosts   []string      `env:"GO_ENV_TEST_HOSTS,min=1,minitems=1,maxitems=3"`
+		Key     []byte        `env:"GO_ENV_TEST_KEY,type=byte,len=3"`
+	}
+
+	withResetEnv(func() {
+		valid := map[string]string{
+			"GO_ENV_TEST_PORT":    "65535",
+			"GO_ENV_TEST_RATIO":   "0.5",
+			"GO_ENV_TEST_TIMEOUT": "30s",
+			"GO_ENV_TEST_TOKEN":   "abcd",
+			"GO_ENV_TEST_NAME":    "café",
+			"GO_ENV_TEST_HOSTS":   "a,b,c",
+			"GO_ENV_TEST_KEY":     "xyz",
+		}
+		for name, value := range valid {
+			os.Setenv(name, value)
+		}
+
+		testStruct := validationStruct{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal(validationStruct{
+			Port:    65535,
+			Ratio:   0.5,
+			Timeout: 30 * time.Second,
+			Token:   "abcd",
+			Name:    "café",
+			Hosts:   []string{"a", "b", "c"},
+			Key:     []byte("xyz"),
+		}, testStruct)
+
+		for _, tc := range []struct{ name, value, err string }{
+			{"GO_ENV_TEST_PORT", "0", "value must be at least 1 but was 0"},
+			{"GO_ENV_TEST_RATIO", "1.5", "value must be at most 1 but was 1.5"},
+			{"GO_ENV_TEST_TIMEOUT", "2m", "value must be at most 1m but was 2m0s"},
+			{"GO_ENV_TEST_TOKEN", "abc", "length must be 4 but was 3"},
+			{"GO_ENV_TEST_NAME", "a", "length must be at least 2 but was 1"},
+			{"GO_ENV_TEST_NAME", "abc1", "value must match pattern \"^[a-zé]+$\""},
+			{"GO_ENV_TEST_HOSTS", "a,,c", "length must be at least 1 but was 0"},
+			{"GO_ENV_TEST_HOSTS", "a,b,c,d", "number of items must be at most 3 but was 4"},
+			{"GO_ENV_TEST_KEY", "wxyz", "length must be 3 but was 4"},
+		} {
+			os.Setenv(tc.name, tc.value)
+			assert.EqualError(env.Parse(&validationStruct{}), fmt.Sprintf("%s: %s", tc.name, tc.err), tc.value)
+			os.Setenv(tc.name, valid[tc.name])
+		}
+	})
+}
+
+func TestParseInvalidValidationOptions(t *testing.T) {
+	assert := require.New(t)
+
+	assert.EqualError(env.Parse(&struct {
+		Value int `env:"GO_ENV_TEST_VALUE,len=3"`
+	}{}), "GO_ENV_TEST_VALUE: len and pattern are only supported for strings, not int")
+	assert.EqualError(env.Parse(&struct {
+		Value string `env:"GO_ENV_TEST_VALUE,minitems=1"`
+	}{}), "GO_ENV_TEST_VALUE: minitems and maxitems are only supported for slices, not string")
+	assert.EqualError(env.Parse(&struct {
+		Value int `env:"GO_ENV_TEST_VALUE,min=1.5"`
+	}{}), "GO_ENV_TEST_VALUE: invalid bound \"1.5\" for int")
+	assert.EqualError(env.Parse(&struct {
+		Value bool `env:"GO_ENV_TEST_VALUE,max=1"`
+	}{}), "GO_ENV_TEST_VALUE: min and max are not supported for bool")
+	assert.EqualError(env.Parse(&struct {
+		Value []int `env:"GO_ENV_TEST_VALUE,minitems=-1"`
+	}{}), "GO_ENV_TEST_VALUE: invalid item count \"minitems=-1\"")
+
+	// options are matched by their full name
+	assert.EqualError(env.Parse(&struct {
+		Value int `env:"GO_ENV_TEST_VALUE,mint=3"`
+	}{}), "GO_ENV_TEST_VALUE: unknown option mint=3")
+	assert.EqualError(env.Parse(&struct {
+		Value string `env:"GO_ENV_TEST_VALUE,lenght=3"`
+	}{}), "GO_ENV_TEST_VALUE: unknown option lenght=3")
+	assert.EqualError(env.Parse(&struct {
+		Value string `env:"GO_ENV_TEST_VALUE,groups=auth"`
+	}{}), "GO_ENV_TEST_VALUE: unknown option groups=auth")
+}
+
+type testTLSConfig struct {
+	Cert string `env:"GO_ENV_TEST_TLS_CERT"`
+	Key  string `env:"GO_ENV_TEST_TLS_KEY"`
+}
+
+func (c testTLSConfig) Validate() error {
+	if (c.Cert == "") != (c.Key == "") {
+		return errors.New("certificate and key must both be set")
+	}
+	return nil
+}
+
+type testServerConfig struct {
+	Host string `env:"GO_ENV_TEST_HOST"`
+	TLS  testTLSConfig
+}
+
+type testValidatedConfig struct {
+	Server  testServerConfig
+	Workers int `env:"GO_ENV_TEST_WORKERS,default=1"`
+	calls   []string
+}
+
+func (c *testValidatedConfig) Validate() error {
+	c.calls = append(c.calls, "config")
+	if c.Workers > 1 && c.Server.Host == "" {
+		return errors.New("host is required for multiple workers")
+	}
+	return nil
+}
+
+func TestParseValidateHook(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_TLS_CERT", "cert.pem")
+		os.Setenv("GO_ENV_TEST_TLS_KEY", "key.pem")
+		testStruct := testValidatedConfig{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal([]string{"config"}, testStruct.calls)
+		assert.Equal("key.pem", testStruct.Server.TLS.Key)
+
+		os.Unsetenv("GO_ENV_TEST_TLS_KEY")
+		assert.Equal("Server.TLS: certificate and key must both be set", env.Parse(&testValidatedConfig{}).Error())
+		assert.Equal("certificate and key must both be set", env.Parse(&testTLSConfig{}).Error())
+
+		os.Unsetenv("GO_ENV_TEST_TLS_CERT")
+		os.Setenv("GO_ENV_TEST_WORKERS", "2")
+		assert.EqualError(env.Parse(&testValidatedConfig{}), "host is required for multiple workers")
+
+		// Validate is not called when parsing a field fails
+		os.Setenv("GO_ENV_TEST_WORKERS", "abc")
+		testStruct = testValidatedConfig{}
+		assert.NotNil(env.Parse(&testStruct))
+		assert.Nil(testStruct.calls)
+	})
+}
+
+func TestParseAggregatedErrors(t *testing.T) {
+	assert := require.New(t)
+
+	type serverConfig struct {
+		Host string `env:"GO_ENV_TEST_HOST,required"`
+		Port int    `env:"GO_ENV_TEST_PORT,min=1"`
+	}
+	type config struct {
+		Server  serverConfig
+		Workers int `env:"GO_ENV_TEST_WORKERS"`
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_PORT", "0")
+		os.Setenv("GO_ENV_TEST_WORKERS", "abc")
+
+		err := env.Parse(&config{})
+		assert.EqualError(err, strings.Join([]string{
+			"GO_ENV_TEST_HOST: value is required but was empty",
+			"GO_ENV_TEST_PORT: value must be at least 1 but was 0",
+			"GO_ENV_TEST_WORKERS: strconv.ParseInt: parsing \"abc\": invalid syntax",
+		}, "\n"))
+
+		var errs env.Errors
+		assert.True(errors.As(err, &errs))
+		assert.Len(errs, 3)
+		assert.Equal("Server.Host", errs[0].FieldPath)
+		assert.Equal("required", errs[0].Option)
+		assert.Equal("", errs[0].Value)
+		assert.True(errors.Is(errs[0], env.ErrRequired))
+		assert.Equal("Server.Port", errs[1].FieldPath)
+		assert.Equal("min", errs[1].Option)
+		assert.Equal("[REDACTED]", errs[1].Value)
+		assert.True(errors.Is(errs[1], env.ErrInvalidValue))
+		assert.False(errors.Is(errs[1], env.ErrRequired))
+
+		var parseErr *env.ParseError
+		assert.True(errors.As(err, &parseErr))
+		assert.Equal("GO_ENV_TEST_HOST", parseErr.Var)
+		assert.True(errors.Is(err, env.ErrRequired))
+		assert.True(errors.Is(err, env.ErrInvalidValue))
+		assert.False(errors.Is(err, env.ErrUnsupportedType))
+
+		var numErr *strconv.NumError
+		assert.True(errors.As(err, &numErr))
+	})
+}
+
+func TestParseAggregatedTagErrors(t *testing.T) {
+	assert := require.New(t)
+
+	testStruct := struct {
+		Invalid uintptr `env:"GO_ENV_TEST_INVALID_TYPE"`
+		Unknown string  `env:"GO_ENV_TEST_UNKNOWN,abc"`
+		Nested  struct {
+			Empty string `env:",required"`
+		}
+	}{}
+
+	err := env.Parse(&testStruct)
+	assert.EqualError(err, strings.Join([]string{
+		"GO_ENV_TEST_INVALID_TYPE: Unrecognized type",
+		"GO_ENV_TEST_UNKNOWN: unknown option abc",
+		"Nested.Empty: env variable name cannot be empty",
+	}, "\n"))
+	assert.True(errors.Is(err, env.ErrUnsupportedType))
+	assert.True(errors.Is(err, env.ErrInvalidTag))
+
+	var errs env.Errors
+	assert.True(errors.As(err, &errs))
+	assert.Equal("abc", errs[1].Option)
+
+	// errors of cached nested plans are not prefixed again
+	assert.EqualError(env.Parse(&testStruct), err.Error())
+}
+
+func TestParseTag(t *testing.T) {
+	assert := require.New(t)
+
+	tag, err := env.ParseTag("GO_ENV_TEST_MODE,default=dev,oneof=dev|prod,required_if=GO_ENV_TEST_TLS:on,secret")
+	assert.Nil(err)
+	assert.Equal("GO_ENV_TEST_MODE", tag.Name)
+	assert.Equal("dev", tag.Default)
+	assert.Equal([]string{"dev", "prod"}, tag.OneOf)
+	assert.Equal([]env.Condition{{Option: "required_if", Var: "GO_ENV_TEST_TLS", Values: []string{"on"}}}, tag.Conditions)
+	assert.True(tag.Secret)
+	assert.Equal("if GO_ENV_TEST_TLS=on", tag.Requirement())
+
+	_, err = env.ParseTag("GO_ENV_TEST_MODE,defualt=dev")
+	assert.EqualError(err, "GO_ENV_TEST_MODE: unknown option defualt=dev")
+	assert.ErrorIs(err, env.ErrInvalidTag)
+}
+
+func TestParseStrict(t *testing.T) {
+	assert := require.New(t)
+
+	type dbConfig struct {
+		Host string `env:"GO_ENV_TEST_DB_HOST,default=localhost"`
+		Port int    `env:"GO_ENV_TEST_DB_PORT,default=5432"`
+	}
+	type config struct {
+		DB   dbConfig
+		Name string `env:"GO_ENV_TEST_NAME"`
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_NAME", "app")
+		os.Setenv("OTHER_DB_HSOT", "ignored")
+		testStruct := config{}
+		assert.Nil(env.ParseStrict(&testStruct, "GO_ENV_TEST_"))
+		assert.Equal(config{DB: dbConfig{Host: "localhost", Port: 5432}, Name: "app"}, testStruct)
+
+		os.Setenv("GO_ENV_TEST_DB_HSOT", "db")
+		os.Setenv("GO_ENV_TEST_UNRELATED_SETTING", "1")
+		os.Setenv("GO_ENV_TEST_DB_PORT", "abc")
+		err := env.ParseStrict(&config{}, "GO_ENV_TEST_")
+		assert.EqualError(err, strings.Join([]string{
+			"GO_ENV_TEST_DB_PORT: strconv.ParseInt: parsing \"abc\": invalid syntax",
+			"GO_ENV_TEST_DB_HSOT: unknown variable, did you mean GO_ENV_TEST_DB_HOST?",
+			"GO_ENV_TEST_UNRELATED_SETTING: unknown variable",
+		}, "\n"))
+		assert.True(errors.Is(err, env.ErrUnknownVariable))
+
+		assert.EqualError(env.ParseStrict(&struct {
+			Value string `env:"GO_ENV_TEST_VALUE,abc"`
+		}{}, "GO_ENV_TEST_"), "GO_ENV_TEST_VALUE: unknown option abc")
+
+		// variables only read by conditions are known
+		os.Clearenv()
+		os.Setenv("GO_ENV_TEST_TLS", "off")
+		assert.Nil(env.ParseStrict(&struct {
+			Cert string `env:"GO_ENV_TEST_CERT,required_if=GO_ENV_TEST_TLS:on"`
+		}{}, "GO_ENV_TEST_"))
+	})
+}
+
+func TestParseConditionalRequired(t *testing.T) {
+	assert := require.New(t)
+
+	type smtpConfig struct {
+		Host string `env:"GO_ENV_TEST_SMTP_HOST"`
+		User string `env:"GO_ENV_TEST_SMTP_USER,required_with=GO_ENV_TEST_SMTP_HOST"`
+	}
+	type config struct {
+		TLSEnabled string `env:"GO_ENV_TEST_TLS_ENABLED,default=true"`
+		TLSCert    string `env:"GO_ENV_TEST_TLS_CERT,required_if=GO_ENV_TEST_TLS_ENABLED:true|1"`
+		Env        string `env:"GO_ENV_TEST_ENV"`
+		Token      string `env:"GO_ENV_TEST_TOKEN,required_unless=GO_ENV_TEST_ENV:dev|test"`
+		SMTP       smtpConfig
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_ENV", "dev")
+		os.Setenv("GO_ENV_TEST_TLS_ENABLED", "false")
+		assert.Nil(env.Parse(&config{}))
+
+		// the default of GO_ENV_TEST_TLS_ENABLED makes the certificate required
+		os.Unsetenv("GO_ENV_TEST_TLS_ENABLED")
+		os.Setenv("GO_ENV_TEST_ENV", "prod")
+		os.Setenv("GO_ENV_TEST_SMTP_HOST", "smtp.example.com")
+		err := env.Parse(&config{})
+		assert.EqualError(err, strings.Join([]string{
+			"GO_ENV_TEST_TLS_CERT: value is required when GO_ENV_TEST_TLS_ENABLED is \"true\", \"1\"",
+			"GO_ENV_TEST_TOKEN: value is required unless GO_ENV_TEST_ENV is \"dev\", \"test\"",
+			"GO_ENV_TEST_SMTP_USER: value is required when GO_ENV_TEST_SMTP_HOST is set",
+		}, "\n"))
+		assert.True(errors.Is(err, env.ErrRequired))
+
+		var parseErr *env.ParseError
+		assert.True(errors.As(err, &parseErr))
+		assert.Equal("required_if", parseErr.Option)
+
+		os.Setenv("GO_ENV_TEST_TLS_CERT", "cert.pem")
+		os.Setenv("GO_ENV_TEST_TOKEN", "secret")
+		os.Setenv("GO_ENV_TEST_SMTP_USER", "user")
+		assert.Nil(env.Parse(&config{}))
+
+		// defaults which don't apply as required is listed first don't resolve conditions
+		os.Setenv("GO_ENV_TEST_C", "y")
+		type requiredFirst struct {
+			C string `env:"GO_ENV_TEST_C,required,default=x"`
+			D string `env:"GO_ENV_TEST_D,required_if=GO_ENV_TEST_C:x"`
+		}
+		assert.Nil(env.Parse(&requiredFirst{}))
+		os.Unsetenv("GO_ENV_TEST_C")
+		assert.EqualError(env.Parse(&requiredFirst{}), "GO_ENV_TEST_C: value is required but was empty")
+	})
+
+	assert.EqualError(env.Parse(&struct {
+		Value string `env:"GO_ENV_TEST_VALUE,required_if=GO_ENV_TEST_OTHER"`
+	}{}), "GO_ENV_TEST_VALUE: invalid condition \"GO_ENV_TEST_OTHER\", expected required_if=VAR:value")
+}
+
+func TestParseGroups(t *testing.T) {
+	assert := require.New(t)
+
+	type oauthConfig struct {
+		Token string `env:"GO_ENV_TEST_OAUTH_TOKEN,group=auth"`
+	}
+	type config struct {
+		APIKey   string `env:"GO_ENV_TEST_API_KEY,group=auth,exclusive,atleastone"`
+		OAuth    oauthConfig
+		Primary  string `env:"GO_ENV_TEST_PRIMARY,group=dsn,atleastone"`
+		Fallback string `env:"GO_ENV_TEST_FALLBACK,group=dsn,default=localhost"`
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_API_KEY", "key")
+		testStruct := config{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal("localhost", testStruct.Fallback)
+
+		os.Setenv("GO_ENV_TEST_OAUTH_TOKEN", "token")
+		err := env.Parse(&config{})
+		assert.EqualError(err, "group auth: only one of GO_ENV_TEST_API_KEY, GO_ENV_TEST_OAUTH_TOKEN may be set, but GO_ENV_TEST_API_KEY, GO_ENV_TEST_OAUTH_TOKEN are")
+		assert.True(errors.Is(err, env.ErrInvalidValue))
+
+		os.Unsetenv("GO_ENV_TEST_API_KEY")
+		os.Unsetenv("GO_ENV_TEST_OAUTH_TOKEN")
+		err = env.Parse(&config{})
+		assert.EqualError(err, "group auth: one of GO_ENV_TEST_API_KEY, GO_ENV_TEST_OAUTH_TOKEN is required")
+		assert.True(errors.Is(err, env.ErrRequired))
+
+		// parsing the nested struct on its own only checks its own members
+		assert.Nil(env.Parse(&oauthConfig{}))
+	})
+
+	assert.EqualError(env.Parse(&struct {
+		Value string `env:"GO_ENV_TEST_VALUE,exclusive"`
+	}{}), "GO_ENV_TEST_VALUE: exclusive and atleastone require a group= option")
+}
+
+func TestParseEmptyValues(t *testing.T) {
+	assert := require.New(t)
+
+	type emptyStruct struct {
+		Plain      string `env:"GO_ENV_TEST_PLAIN,default=plain"`
+		AllowEmpty string `env:"GO_ENV_TEST_ALLOW_EMPTY,allowempty,default=allowed"`
+		Required   string `env:"GO_ENV_TEST_REQUIRED,required,allowempty"`
+		Count      int    `env:"GO_ENV_TEST_COUNT,allowempty"`
+		NotEmpty   string `env:"GO_ENV_TEST_NOT_EMPTY,notempty"`
+		Untouched  int    `env:"GO_ENV_TEST_UNTOUCHED"`
+		Rune       rune   `env:"GO_ENV_TEST_RUNE,type=rune"`
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_PLAIN", "")
+		os.Setenv("GO_ENV_TEST_ALLOW_EMPTY", "")
+		os.Setenv("GO_ENV_TEST_REQUIRED", "")
+		os.Setenv("GO_ENV_TEST_COUNT", "")
+		os.Setenv("GO_ENV_TEST_RUNE", "")
+
+		testStruct := emptyStruct{AllowEmpty: "prefilled", Count: 3, Untouched: 7, Rune: 'x'}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal(emptyStruct{Plain: "plain", Untouched: 7, Rune: 'x'}, testStruct)
+
+		os.Unsetenv("GO_ENV_TEST_ALLOW_EMPTY")
+		os.Unsetenv("GO_ENV_TEST_REQUIRED")
+		os.Setenv("GO_ENV_TEST_NOT_EMPTY", "")
+		err := env.Parse(&emptyStruct{})
+		assert.EqualError(err, strings.Join([]string{
+			"GO_ENV_TEST_REQUIRED: value is required but was empty",
+			"GO_ENV_TEST_NOT_EMPTY: value must not be empty",
+		}, "\n"))
+
+		var errs env.Errors
+		assert.True(errors.As(err, &errs))
+		assert.Equal("notempty", errs[1].Option)
+		assert.True(errors.Is(errs[1], env.ErrInvalidValue))
+
+		// conditions and groups resolve set but empty variables like the fields reading them
+		type conditionStruct struct {
+			Plain      string `env:"GO_ENV_TEST_PLAIN,group=one,atleastone"`
+			AllowEmpty string `env:"GO_ENV_TEST_ALLOW_EMPTY,allowempty,group=one"`
+			WithPlain  string `env:"GO_ENV_TEST_WITH_PLAIN,required_with=GO_ENV_TEST_PLAIN"`
+			WithEmpty  string `env:"GO_ENV_TEST_WITH_EMPTY,required_with=GO_ENV_TEST_ALLOW_EMPTY"`
+		}
+		os.Setenv("GO_ENV_TEST_ALLOW_EMPTY", "")
+		assert.EqualError(env.Parse(&conditionStruct{}), "GO_ENV_TEST_WITH_EMPTY: value is required when GO_ENV_TEST_ALLOW_EMPTY is set")
+		os.Unsetenv("GO_ENV_TEST_ALLOW_EMPTY")
+		assert.EqualError(env.Parse(&conditionStruct{}), "group one: one of GO_ENV_TEST_PLAIN, GO_ENV_TEST_ALLOW_EMPTY is required")
+	})
+
+	assert.EqualError(env.Parse(&struct {
+		Value string `env:"GO_ENV_TEST_VALUE,allowempty,notempty"`
+	}{}), "GO_ENV_TEST_VALUE: allowempty and notempty cannot be combined")
+}
+
+func TestUsage(t *testing.T) {
+	assert := require.New(t)
+
+	type dbConfig struct {
+		Host  string   `env:"GO_ENV_TEST_DB_HOST,default=localhost" desc:"database host"`
+		Hosts [][]byte `env:"GO_ENV_TEST_DB_REPLICAS,separators=;,type=byte" desc:"replica groups"`
+	}
+	type config struct {
+		Name    string        `env:"GO_ENV_TEST_NAME,required,default=api" desc:"name of the service"`
+		Timeout time.Duration `env:"GO_ENV_TEST_TIMEOUT,default=30s"`
+		Tags    []string      `env:"GO_ENV_TEST_TAGS,separator=:"`
+		Cert    string        `env:"GO_ENV_TEST_CERT,required_if=GO_ENV_TEST_TLS:true,required_with=GO_ENV_TEST_KEY"`
+		DB      dbConfig
+	}
+
+	var buf strings.Builder
+	assert.Nil(env.Usage(&config{}, &buf))
+	assert.Equal(`VARIABLE                 TYPE           DEFAULT    REQUIRED                                       SEPARATOR  DESCRIPTION
+GO_ENV_TEST_NAME         string                    yes                                                       name of the service
+GO_ENV_TEST_TIMEOUT      time.Duration  30s
+GO_ENV_TEST_TAGS         []string                                                                 :
+GO_ENV_TEST_CERT         string                    if GO_ENV_TEST_TLS=true, with GO_ENV_TEST_KEY
+GO_ENV_TEST_DB_HOST      string         localhost                                                            database host
+GO_ENV_TEST_DB_REPLICAS  [][]uint8                                                                ;          replica groups
+`, trimLines(buf.String()))
+
+	assert.EqualError(env.Usage(config{}, &buf), "Expected a pointer value")
+}
+
+func TestWriteTemplate(t *testing.T) {
+	assert := require.New(t)
+
+	type dbConfig struct {
+		Host string `env:"GO_ENV_TEST_DB_HOST,default=localhost" desc:"database host"`
+	}
+	type config struct {
+		Name    string        `env:"GO_ENV_TEST_NAME,required" desc:"name of the service"`
+		Timeout time.Duration `env:"GO_ENV_TEST_TIMEOUT,default=30s"`
+		Tags    []string      `env:"GO_ENV_TEST_TAGS,separator=:,required_with=GO_ENV_TEST_NAME"`
+		Motd    string        `env:"GO_ENV_TEST_MOTD"`
+		DB      dbConfig
+	}
+
+	var buf strings.Builder
+	assert.Nil(env.WriteTemplate(&config{}, &buf))
+	assert.Equal(`# name of the service
+# type: string, required
+GO_ENV_TEST_NAME=
+
+# type: time.Duration, default: 30s
+# GO_ENV_TEST_TIMEOUT=30s
+
+# type: []string, required with GO_ENV_TEST_NAME, separator: :
+# GO_ENV_TEST_TAGS=
+
+# type: string
+# GO_ENV_TEST_MOTD=
+
+# database host
+# type: string, default: localhost
+# GO_ENV_TEST_DB_HOST=localhost
+`, buf.String())
+
+	buf.Reset()
+	assert.Nil(env.WriteTemplate(&config{Name: "api", Timeout: time.Minute, Tags: []string{"a", "b"}, Motd: "hello # world"}, &buf))
+	assert.Contains(buf.String(), "\nGO_ENV_TEST_NAME=api\n")
+	assert.Contains(buf.String(), "\nGO_ENV_TEST_TIMEOUT=1m0s\n")
+	assert.Contains(buf.String(), "\nGO_ENV_TEST_TAGS=a:b\n")
+	assert.Contains(buf.String(), "\nGO_ENV_TEST_MOTD=\"hello # world\"\n")
+	assert.Contains(buf.String(), "\n# GO_ENV_TEST_DB_HOST=localhost\n")
+
+	assert.EqualError(env.WriteTemplate(&config{Tags: []string{"a:b"}}, &buf), "GO_ENV_TEST_TAGS: value \"a:b\" contains the separator \":\", which requires the quoted option")
+
+	buf.Reset()
+	assert.Nil(env.WriteTemplate(&struct {
+		Host string `env:"GO_ENV_TEST_HOST" desc:"address to listen on,\nall interfaces if empty"`
+	}{}, &buf))
+	assert.Equal("# address to listen on,\n# all interfaces if empty\n# type: string\n# GO_ENV_TEST_HOST=\n", buf.String())
+}
+
+func TestMarshal(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		expected := genValidEnvStruct()
+		vars, err := env.Marshal(expected)
+		assert.Nil(err)
+		assert.Equal(strconv.Itoa(expected.IntValue), vars["GO_ENV_TEST_INT_VALUE"])
+		assert.Equal("1s,2h0m0s", vars["GO_ENV_TEST_TIME_DURATION_SLICE_VALUE"])
+
+		os.Clearenv()
+		for name, value := range vars {
+			os.Setenv(name, value)
+		}
+		testStruct := &testEnvStruct{}
+		assert.Nil(env.Parse(testStruct))
+		assert.Equal(expected, testStruct)
+	})
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	assert := require.New(t)
+
+	type roundTripStruct struct {
+		Runes    []rune            `env:"GO_ENV_TEST_RUNES,type=rune"`
+		Rune     rune              `env:"GO_ENV_TEST_RUNE,type=rune"`
+		Byte     byte              `env:"GO_ENV_TEST_BYTE,type=byte"`
+		Mode     os.FileMode       `env:"GO_ENV_TEST_MODE"`
+		Quoted   []string          `env:"GO_ENV_TEST_QUOTED,quoted,trim"`
+		Nested   [][]string        `env:"GO_ENV_TEST_NESTED,separators=;|,,quoted"`
+		Ports    []testPort        `env:"GO_ENV_TEST_PORTS,separator=:"`
+		Flags    map[string]bool   `env:"GO_ENV_TEST_FLAGS,format=json"`
+		Enabled  bool              `env:"GO_ENV_TEST_ENABLED,default=true"`
+		Untagged map[string]string // not read from the environment
+	}
+
+	withResetEnv(func() {
+		expected := roundTripStruct{
+			Runes:   []rune("a→\\tU+é\x00"),
+			Rune:    '\t',
+			Byte:    0xff,
+			Mode:    os.ModeSetgid | 0750,
+			Quoted:  []string{" padded ", `say "hi", \o/`},
+			Nested:  [][]string{{"a;b", "c,d"}, {`"e"`}},
+			Ports:   []testPort{80, 443},
+			Flags:   map[string]bool{"beta": true},
+			Enabled: false,
+		}
+		vars, err := env.Marshal(&expected)
+		assert.Nil(err)
+		assert.Equal("2750", vars["GO_ENV_TEST_MODE"])
+		assert.Equal("false", vars["GO_ENV_TEST_ENABLED"])
+
+		os.Clearenv()
+		for name, value := range vars {
+			os.Setenv(name, value)
+		}
+		testStruct := roundTripStruct{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal(expected, testStruct)
+
+		assert.Equal([]string{"GO_ENV_TEST_ENABLED=false", "GO_ENV_TEST_PORTS=80:443"}, env.Environ(&roundTripStruct{Ports: []testPort{80, 443}}))
+	})
+
+	_, err := env.Marshal(&struct {
+		Values []string `env:"GO_ENV_TEST_VALUES"`
+	}{Values: []string{"a,b"}})
+	assert.EqualError(err, "GO_ENV_TEST_VALUES: value \"a,b\" contains the separator \",\", which requires the quoted option")
+}
+
+func TestParseSecret(t *testing.T) {
+	assert := require.New(t)
+
+	type config struct {
+		Password env.Secret[string]   `env:"GO_ENV_TEST_PASSWORD,required"`
+		Keys     env.Secret[[]string] `env:"GO_ENV_TEST_KEYS,separator=:"`
+		Pin      int                  `env:"GO_ENV_TEST_PIN,secret"`
+		User     string               `env:"GO_ENV_TEST_USER"`
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_PASSWORD", "hunter2")
+		os.Setenv("GO_ENV_TEST_KEYS", "k1:k2")
+		os.Setenv("GO_ENV_TEST_USER", "admin")
+
+		testStruct := config{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal("hunter2", testStruct.Password.Value())
+		assert.Equal([]string{"k1", "k2"}, testStruct.Keys.Value())
+
+		for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x"} {
+			assert.NotContains(fmt.Sprintf(format, testStruct), "hunter2", format)
+			assert.NotContains(fmt.Sprintf(format, testStruct), "k1", format)
+		}
+		assert.Equal("{Password:[REDACTED] Keys:[REDACTED] Pin:0 User:admin}", fmt.Sprintf("%+v", testStruct))
+
+		data, err := json.Marshal(testStruct)
+		assert.Nil(err)
+		assert.Equal(`{"Password":"[REDACTED]","Keys":"[REDACTED]","Pin":0,"User":"admin"}`, string(data))
+
+		var buf strings.Builder
+		slog.New(slog.NewTextHandler(&buf, nil)).Info("config", "password", testStruct.Password)
+		assert.Contains(buf.String(), "password=[REDACTED]")
+
+		vars, err := env.Marshal(&testStruct)
+		assert.Nil(err)
+		assert.Equal("hunter2", vars["GO_ENV_TEST_PASSWORD"])
+
+		buf.Reset()
+		assert.Nil(env.WriteTemplate(&testStruct, &buf))
+		assert.NotContains(buf.String(), "hunter2")
+		assert.Contains(buf.String(), "# type: env.Secret[string], required, secret\nGO_ENV_TEST_PASSWORD=\n")
+
+		os.Setenv("GO_ENV_TEST_PIN", "12a4")
+		err = env.Parse(&config{})
+		assert.EqualError(err, "GO_ENV_TEST_PIN: invalid value")
+		assert.True(errors.Is(err, env.ErrInvalidValue))
+		var numErr *strconv.NumError
+		assert.True(errors.As(err, &numErr))
+	})
+
+	assert.Equal("s3cr3t", env.NewSecret("s3cr3t").Value())
+}
+
+func TestJSONSchema(t *testing.T) {
+	assert := require.New(t)
+
+	type dbConfig struct {
+		Host     string             `env:"GO_ENV_TEST_DB_HOST,default=localhost,pattern=^[a-z.]+$" desc:"database host"`
+		Password env.Secret[string] `env:"GO_ENV_TEST_DB_PASSWORD,required_with=GO_ENV_TEST_DB_HOST"`
+	}
+	type config struct {
+		Port    int             `env:"GO_ENV_TEST_PORT,required,default=8080,min=1,max=65535"`
+		Level   testLevel       `env:"GO_ENV_TEST_LEVEL,default=info"`
+		Mode    int             `env:"GO_ENV_TEST_MODE,oneof=1|2"`
+		Timeout time.Duration   `env:"GO_ENV_TEST_TIMEOUT,default=30s,min=1s"`
+		Started time.Time       `env:"GO_ENV_TEST_STARTED"`
+		Hosts   [][]string      `env:"GO_ENV_TEST_HOSTS,separators=;|,,minitems=1,len=3,default='a,b;c'"`
+		Enabled bool            `env:"GO_ENV_TEST_ENABLED,default=true"`
+		Ratio   float32         `env:"GO_ENV_TEST_RATIO,default=0.5"`
+		Key     []byte          `env:"GO_ENV_TEST_KEY,type=byte"`
+		Umask   os.FileMode     `env:"GO_ENV_TEST_UMASK,default=022"`
+		Flags   map[string]bool `env:"GO_ENV_TEST_FLAGS,format=json,default='{\"beta\":true}'"`
+		DB      dbConfig
+	}
+
+	schema, err := env.JSONSchema(&config{})
+	assert.Nil(err)
+	assert.JSONEq(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"GO_ENV_TEST_PORT": {"type": "integer", "minimum": 1, "maximum": 65535},
+			"GO_ENV_TEST_LEVEL": {"type": "string", "enum": ["debug", "info", "warn"], "default": "info"},
+			"GO_ENV_TEST_MODE": {"type": "integer", "enum": [1, 2]},
+			"GO_ENV_TEST_TIMEOUT": {"type": "string", "pattern": "^[-+]?(0|([0-9]+(\\.[0-9]*)?|\\.[0-9]+)(ns|us|µs|ms|s|m|h))+$", "default": "30s"},
+			"GO_ENV_TEST_STARTED": {"type": "string", "format": "date-time"},
+			"GO_ENV_TEST_HOSTS": {
+				"type": "array", "minItems": 1, "default": [["a", "b"], ["c"]],
+				"items": {"type": "array", "items": {"type": "string", "minLength": 3, "maxLength": 3}}
+			},
+			"GO_ENV_TEST_ENABLED": {"type": "boolean", "default": true},
+			"GO_ENV_TEST_RATIO": {"type": "number", "default": 0.5},
+			"GO_ENV_TEST_KEY": {"type": "string"},
+			"GO_ENV_TEST_UMASK": {"type": "string", "pattern": "^(0[oO]?)?[0-7]{1,4}$|^-?[-r][-w][-xsS][-r][-w][-xsS][-r][-w][-xtT]$", "default": "0022"},
+			"GO_ENV_TEST_FLAGS": {"default": {"beta": true}},
+			"GO_ENV_TEST_DB_HOST": {"type": "string", "pattern": "^[a-z.]+$", "default": "localhost", "description": "database host"},
+			"GO_ENV_TEST_DB_PASSWORD": {"type": "string", "writeOnly": true}
+		},
+		"required": ["GO_ENV_TEST_PORT"],
+		"dependentRequired": {"GO_ENV_TEST_DB_HOST": ["GO_ENV_TEST_DB_PASSWORD"]}
+	}`, string(schema))
+
+	_, err = env.JSONSchema(&struct {
+		Value int `env:"GO_ENV_TEST_VALUE,default=abc"`
+	}{})
+	assert.EqualError(err, "GO_ENV_TEST_VALUE: strconv.ParseInt: parsing \"abc\": invalid syntax")
+}
+
+func TestWriteKubernetes(t *testing.T) {
+	assert := require.New(t)
+
+	type config struct {
+		Host     string             `env:"GO_ENV_TEST_HOST,default=localhost" desc:"address to listen on"`
+		Name     string             `env:"GO_ENV_TEST_NAME,required"`
+		Password env.Secret[string] `env:"GO_ENV_TEST_PASSWORD,required"`
+		Token    string             `env:"GO_ENV_TEST_TOKEN,secret"`
+	}
+
+	var buf strings.Builder
+	assert.Nil(env.WriteKubernetes(&config{Name: "api \"v2\"", Token: "abc"}, "api-secrets", &buf))
+	assert.Equal(`env:
+  # address to listen on
+  # - name: GO_ENV_TEST_HOST
+  #   value: "localhost"
+  - name: GO_ENV_TEST_NAME
+    value: "api \"v2\""
+  - name: GO_ENV_TEST_PASSWORD
+    valueFrom:
+      secretKeyRef:
+        name: api-secrets
+        key: GO_ENV_TEST_PASSWORD
+  - name: GO_ENV_TEST_TOKEN
+    valueFrom:
+      secretKeyRef:
+        name: api-secrets
+        key: GO_ENV_TEST_TOKEN
+        optional: true
+`, buf.String())
+}
+
+func TestWriteCompose(t *testing.T) {
+	assert := require.New(t)
+
+	type config struct {
+		Host     string             `env:"GO_ENV_TEST_HOST,default=localhost" desc:"address to listen on,\nall interfaces if empty"`
+		Name     string             `env:"GO_ENV_TEST_NAME,required"`
+		Price    string             `env:"GO_ENV_TEST_PRICE"`
+		Password env.Secret[string] `env:"GO_ENV_TEST_PASSWORD,required"`
+		Token    string             `env:"GO_ENV_TEST_TOKEN,secret"`
+	}
+
+	var buf strings.Builder
+	assert.Nil(env.WriteCompose(&config{Price: "$5", Token: "abc"}, &buf))
+	assert.Equal(`environment:
+  # address to listen on,
+  # all interfaces if empty
+  # GO_ENV_TEST_HOST: "localhost"
+  GO_ENV_TEST_NAME: ""
+  GO_ENV_TEST_PRICE: "$$5"
+  GO_ENV_TEST_PASSWORD: ${GO_ENV_TEST_PASSWORD:?GO_ENV_TEST_PASSWORD is required}
+  GO_ENV_TEST_TOKEN: ${GO_ENV_TEST_TOKEN}
+`, buf.String())
+}
+
+func TestExplain(t *testing.T) {
+	assert := require.New(t)
+
+	type dbConfig struct {
+		Password env.Secret[string] `env:"GO_ENV_TEST_DB_PASSWORD"`
+	}
+	type config struct {
+		Host  string        `env:"GO_ENV_TEST_HOST,default=localhost"`
+		Port  int           `env:"GO_ENV_TEST_PORT,default=8080"`
+		Wait  time.Duration `env:"GO_ENV_TEST_WAIT"`
+		Empty string        `env:"GO_ENV_TEST_EMPTY,default=none"`
+		Pin   int           `env:"GO_ENV_TEST_PIN,secret"`
+		DB    dbConfig
+	}
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_PORT", "9090")
+		os.Setenv("GO_ENV_TEST_EMPTY", "")
+		os.Setenv("GO_ENV_TEST_PIN", "1234")
+		os.Setenv("GO_ENV_TEST_DB_PASSWORD", "hunter2")
+
+		cfg := &config{}
+		explanations, err := env.Explain(cfg)
+		assert.Nil(err)
+		assert.Equal(9090, cfg.Port)
+		assert.Equal([]env.Explanation{
+			{Var: "GO_ENV_TEST_HOST", FieldPath: "Host", Raw: "localhost", Source: env.SourceDefault, Value: "localhost"},
+			{Var: "GO_ENV_TEST_PORT", FieldPath: "Port", Raw: "9090", Source: env.SourceEnv, Value: 9090},
+			{Var: "GO_ENV_TEST_WAIT", FieldPath: "Wait", Raw: "", Source: env.SourceUnset, Value: time.Duration(0)},
+			{Var: "GO_ENV_TEST_EMPTY", FieldPath: "Empty", Raw: "none", Source: env.SourceDefault, Value: "none"},
+			{Var: "GO_ENV_TEST_PIN", FieldPath: "Pin", Raw: "[REDACTED]", Source: env.SourceEnv, Value: "[REDACTED]"},
+			{Var: "GO_ENV_TEST_DB_PASSWORD", FieldPath: "DB.Password", Raw: "[REDACTED]", Source: env.SourceEnv, Value: "[REDACTED]"},
+		}, explanations)
+		assert.Equal("GO_ENV_TEST_PORT=9090 (env) -> 9090", explanations[1].String())
+
+		// sources are those of the values parsed, not of the environment changed afterwards
+		os.Unsetenv("GO_ENV_TEST_PORT")
+		os.Setenv("GO_ENV_TEST_WAIT", "bad")
+		cfg = &config{}
+		explanations, err = env.Explain(cfg)
+		assert.ErrorIs(err, env.ErrInvalidValue)
+		assert.Equal(env.Explanation{Var: "GO_ENV_TEST_PORT", FieldPath: "Port", Raw: "8080", Source: env.SourceDefault, Value: 8080}, explanations[1])
+		assert.Equal(env.Explanation{Var: "GO_ENV_TEST_WAIT", FieldPath: "Wait", Raw: "bad", Source: env.SourceEnv, Value: time.Duration(0)}, explanations[2])
+
+		_, err = env.Explain(config{})
+		assert.EqualError(err, "Expected a pointer value")
+	})
+}
+
+func TestCheckTag(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Nil(env.CheckTag(reflect.TypeOf(uint16(0)), "PORT,default=8080,max=65000"))
+	assert.Nil(env.CheckTag(reflect.TypeOf(env.Secret[int]{}), "PIN,default=1234"))
+	assert.EqualError(env.CheckTag(reflect.TypeOf(""), "HOST,defualt=localhost"), "HOST: unknown option defualt=localhost")
+	assert.EqualError(env.CheckTag(reflect.TypeOf(map[string]int{}), "LIMITS"), "LIMITS: Unrecognized type")
+	assert.EqualError(env.CheckTag(reflect.TypeOf(time.Duration(0)), "WAIT,default=5"), "WAIT: time: missing unit in duration \"5\"")
+	assert.EqualError(env.CheckTag(reflect.TypeOf(0), "LEVEL,default=20,max=10"), "LEVEL: value must be at most 10 but was 20")
+	assert.EqualError(env.CheckTag(reflect.TypeOf(env.Secret[int]{}), "PIN,default=abc"), "PIN: invalid value")
+
+	var parseErr *env.ParseError
+	assert.True(errors.As(env.CheckTag(reflect.TypeOf(0), "PORT,default=http"), &parseErr))
+	assert.Equal("default", parseErr.Option)
+	assert.ErrorIs(parseErr, env.ErrInvalidValue)
+}
+
+type testPort uint16
+
+type testRatio float32
+
+type testKey []byte
+
+func TestParseNamedTypeSlices(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_PORTS", "80,443")
+		os.Setenv("GO_ENV_TEST_RATIOS", "0.5,1")
+		os.Setenv("GO_ENV_TEST_LEVELS", "debug,warn")
+		os.Setenv("GO_ENV_TEST_KEY", "secret")
+		os.Setenv("GO_ENV_TEST_PORT_GROUPS", "80,443;8080")
+
+		testStruct := struct {
+			Port       testPort     `env:"GO_ENV_TEST_PORT,default=8080"`
+			Ports      []testPort   `env:"GO_ENV_TEST_PORTS"`
+			Ratios     []testRatio  `env:"GO_ENV_TEST_RATIOS"`
+			Levels     []testLevel  `env:"GO_ENV_TEST_LEVELS"`
+			Key        testKey      `env:"GO_ENV_TEST_KEY,type=byte"`
+			PortGroups [][]testPort `env:"GO_ENV_TEST_PORT_GROUPS,separators=;"`
+		}{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal(testPort(8080), testStruct.Port)
+		assert.Equal([]testPort{80, 443}, testStruct.Ports)
+		assert.Equal([]testRatio{0.5, 1}, testStruct.Ratios)
+		assert.Equal([]testLevel{"debug", "warn"}, testStruct.Levels)
+		assert.Equal(testKey("secret"), testStruct.Key)
+		assert.Equal([][]testPort{{80, 443}, {8080}}, testStruct.PortGroups)
+
+		os.Setenv("GO_ENV_TEST_PORTS", "80,65536")
+		assert.EqualError(env.Parse(&testStruct), "GO_ENV_TEST_PORTS: strconv.ParseUint: parsing \"65536\": value out of range")
+
+		os.Setenv("GO_ENV_TEST_PORTS", "80")
+		os.Setenv("GO_ENV_TEST_LEVELS", "debug,error")
+		assert.EqualError(env.Parse(&testStruct), "GO_ENV_TEST_LEVELS: invalid value \"error\", valid options are: \"debug\", \"info\", \"warn\"")
+	})
+}
+
+func TestParseCachedPlan(t *testing.T) {
+	assert := require.New(t)
+
+	withResetEnv(func() {
+		os.Setenv("GO_ENV_TEST_STRING_VALUE", "abc")
+
+		type cachedStruct struct {
+			StringValue string `env:"GO_ENV_TEST_STRING_VALUE,required"`
+		}
+		type invalidCachedStruct struct {
+			StringValue string `env:"GO_ENV_TEST_STRING_VALUE,abc"`
+		}
+
+		// repeated and concurrent parses of the same type share one plan
+		var wg sync.WaitGroup
+		results := make([]cachedStruct, 8)
+		errs := make([]error, len(results))
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = env.Parse(&results[i])
+			}(i)
+		}
+		wg.Wait()
+		for i := range results {
+			assert.Nil(errs[i])
+			assert.Equal("abc", results[i].StringValue)
+		}
+
+		os.Setenv("GO_ENV_TEST_STRING_VALUE", "def")
+		testStruct := cachedStruct{}
+		assert.Nil(env.Parse(&testStruct))
+		assert.Equal("def", testStruct.StringValue)
+
+		for i := 0; i < 2; i++ {
+			assert.EqualError(env.Parse(&invalidCachedStruct{}), "GO_ENV_TEST_STRING_VALUE: unknown option abc")
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	withResetEnv(func() {
+		setupAllEnvVariables(genValidEnvStruct(), env.DefaultSeparator)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := env.Parse(&nestedTestEnvStruct{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseScalars(b *testing.B) {
+	type config struct {
+		Host    string        `env:"GO_ENV_TEST_STRING_VALUE,required"`
+		Port    uint16        `env:"GO_ENV_TEST_UINT16_VALUE,default=8080"`
+		Debug   bool          `env:"GO_ENV_TEST_BOOL_VALUE"`
+		Timeout time.Duration `env:"GO_ENV_TEST_TIME_DURATION_VALUE,default=5s"`
+		Level   string        `env:"GO_ENV_TEST_LEVEL,oneof=debug|info,default=info"`
+	}
+
+	withResetEnv(func() {
+		setupAllEnvVariables(genValidEnvStruct(), env.DefaultSeparator)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := env.Parse(&config{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func withResetEnv(cb func()) {
 	existing := os.Environ()
 	defer func() {
@@ -821,6 +2075,15 @@ func genValidEnvStruct() *testEnvStruct {
 	}
 }
 
+// trimLines removes the padding tabwriter leaves at the end of lines with empty last columns
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for idx, line := range lines {
+		lines[idx] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
 func sliceToString(sep, format string, v []interface{}) string {
 	slice := make([]string, len(v))
 	for idx, val := range v {
@@ -0,0 +1,117 @@
+package env
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrRequired is reported for required variables which are unset or empty
+	ErrRequired = errors.New("value is required but was empty")
+	// ErrInvalidValue is reported for values which can't be converted to the type of their
+	// field or fail one of its constraints, including errors returned by Validate
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrUnsupportedType is reported for fields of a type Parse can't convert values to
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrInvalidTag is reported for env tags with an empty name or invalid options
+	ErrInvalidTag = errors.New("invalid tag")
+)
+
+// redactedValue replaces values in errors, as they may be credentials
+const redactedValue = "[REDACTED]"
+
+// ParseError describes a field, or a struct implementing Validate, which failed to parse.
+// It matches one of ErrRequired, ErrInvalidValue, ErrUnsupportedType, ErrInvalidTag or
+// ErrUnknownVariable with errors.Is.
+type ParseError struct {
+	Var       string // the environment variable, empty for errors returned by Validate
+	FieldPath string // the path of the field from the parsed struct, e.g. Server.TLS.Cert
+	Value     string // the redacted value, empty if the variable was unset or empty
+	Option    string // the name of the tag option which failed, e.g. required or min
+	Err       error
+
+	kind error
+}
+
+// Error formats the error prefixed with the variable name, or the field path for errors
+// returned by Validate of nested structs
+func (e *ParseError) Error() string {
+	switch {
+	case e.Var != "":
+		return asParseError(e.Var, e.Err.Error()).Error()
+	case e.FieldPath != "":
+		return asParseError(e.FieldPath, e.Err.Error()).Error()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error describing the kind of e
+func (e *ParseError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// prefix prepends the name of the struct field holding the field or struct of e to its path
+func (e *ParseError) prefix(fieldName string) {
+	if e.FieldPath == "" {
+		e.FieldPath = fieldName
+	} else {
+		e.FieldPath = fieldName + "." + e.FieldPath
+	}
+}
+
+// NewError returns the error of the variable name read by the field at fieldPath, matching
+// kind with errors.Is, for parsers generated by go-env-gen to report errors like Parse.
+// The value is redacted.
+func NewError(name, fieldPath, value, option string, err, kind error) *ParseError {
+	return &ParseError{Var: name, FieldPath: fieldPath, Value: redact(value), Option: option, Err: err, kind: kind}
+}
+
+// optionError returns the error of a failed option, completed by the field it belongs to
+func optionError(option string, err error) *ParseError {
+	return &ParseError{Option: option, Err: err}
+}
+
+// Errors holds every error of a Parse call in the order of the struct fields
+type Errors []*ParseError
+
+// Error joins the messages of all errors, one per line
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for idx, err := range e {
+		messages[idx] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the errors for use with errors.Is and errors.As
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for idx, err := range e {
+		errs[idx] = err
+	}
+	return errs
+}
+
+// prefixed returns copies of errs with fieldName prepended to their path, leaving errs
+// untouched as they may be cached along with their plan
+func (e Errors) prefixed(fieldName string) Errors {
+	errs := make(Errors, len(e))
+	for idx, err := range e {
+		copied := *err
+		copied.prefix(fieldName)
+		errs[idx] = &copied
+	}
+	return errs
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
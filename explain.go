@@ -0,0 +1,75 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// Source is where the value of a variable was taken from
+type Source string
+
+const (
+	SourceEnv     Source = "env"     // the variable is set in the environment
+	SourceDefault Source = "default" // the variable is unset and the default= option applies
+	SourceUnset   Source = "unset"   // the variable is unset without a default
+)
+
+// Explanation describes the value of a single variable
+type Explanation struct {
+	Var       string
+	FieldPath string
+	Raw       string      // the value read, redacted for secret fields
+	Source    Source      // where Raw was taken from
+	Value     interface{} // the current value of the field, redacted for secret fields
+}
+
+// String formats the explanation as NAME=raw (source) -> value
+func (e Explanation) String() string {
+	return fmt.Sprintf("%s=%s (%s) -> %v", e.Var, e.Raw, e.Source, e.Value)
+}
+
+// Explain parses the struct v points to like Parse, returning an Explanation for every
+// variable it read, including nested structs, in declaration order. It reports whether
+// values were configured or fall back to their default, e.g. at debug level on startup.
+// Explanations are returned along with the errors of Parse, describing the values which
+// were read for fields which failed.
+//
+// Example usage:
+//
+//	explanations, err := env.Explain(&cfg)
+//	if err != nil {
+//		return err
+//	}
+//	for _, e := range explanations {
+//		slog.Debug("config", "var", e.Var, "source", e.Source, "value", e.Value)
+//	}
+func Explain(v interface{}) ([]Explanation, error) {
+	var explanations []Explanation
+	if err := parseStruct(v, &explanations); err != nil {
+		return explanations, err
+	}
+	return explanations, nil
+}
+
+// explain describes field, the field of f at path, parsed from value looked up from source
+func (f *fieldPlan) explain(field reflect.Value, path, value string, source Source) Explanation {
+	e := Explanation{Var: f.name, FieldPath: path, Raw: value, Source: source, Value: field.Interface()}
+	if f.secret {
+		e.Raw, e.Value = redact(value), redactedValue
+	}
+	return e
+}
+
+// lookup returns the value of the variable of f from the environment, or its default if
+// unset. Empty variables count as unset unless allowed with allowempty or rejected with
+// notempty. Defaults of fields listing required before default= don't apply.
+func (f *fieldPlan) lookup() (string, Source) {
+	if value, ok := os.LookupEnv(f.name); ok && (value != "" || f.allowEmpty || f.notEmpty) {
+		return value, SourceEnv
+	}
+	if defaultValue := f.appliedDefault(); defaultValue != "" {
+		return defaultValue, SourceDefault
+	}
+	return "", SourceUnset
+}
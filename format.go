@@ -0,0 +1,264 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// formatter converts the value of a field back to the environment value it is parsed from
+type formatter func(field reflect.Value) (string, error)
+
+// newFormatter returns the formatter for fields of type t with the given options, the
+// inverse of the parser returned by newParser
+func newFormatter(t reflect.Type, opts valueOptions) (formatter, error) {
+	switch {
+	case opts.format == constFormatJSON:
+		return formatJSON, nil
+	case t.Kind() == reflect.Slice:
+		return newSliceFormatter(t, opts, nil)
+	}
+
+	scalar, err := newScalarFormatter(t, opts.aliasType)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.quoted && !opts.trim {
+		return scalar, nil
+	}
+	return escapingFormatter(scalar, nil, opts), nil
+}
+
+// newSliceFormatter returns a formatter joining the formatted elements of a slice with the
+// separator of its level. Elements are escaped against the separators of all levels.
+func newSliceFormatter(t reflect.Type, opts valueOptions, outer []string) (formatter, error) {
+	separator := DefaultSeparator
+	if len(opts.separators) > 0 && opts.separators[0] != "" {
+		separator = opts.separators[0]
+	}
+	separators := append(outer[:len(outer):len(outer)], separator)
+
+	var (
+		elem formatter
+		err  error
+	)
+	switch elemKind := t.Elem().Kind(); {
+	case elemKind == reflect.Slice:
+		inner := opts
+		inner.separators = nil
+		if len(opts.separators) > 1 {
+			inner.separators = opts.separators[1:]
+		}
+		if elem, err = newSliceFormatter(t.Elem(), inner, separators); err != nil {
+			return nil, err
+		}
+
+	// byte and rune slices hold a single value when aliased
+	case elemKind == reflect.Uint8 && opts.aliasType == constAliasTypeByte,
+		elemKind == reflect.Int32 && opts.aliasType == constAliasTypeRune:
+		return escapingFormatter(newAliasedSliceFormatter(opts.aliasType), separators, opts), nil
+
+	default:
+		if elem, err = newScalarFormatter(t.Elem(), opts.aliasType); err != nil {
+			return nil, err
+		}
+		elem = escapingFormatter(elem, separators, opts)
+	}
+
+	return func(field reflect.Value) (string, error) {
+		data := make([]string, field.Len())
+		for idx := range data {
+			var err error
+			if data[idx], err = elem(field.Index(idx)); err != nil {
+				return "", err
+			}
+		}
+		return strings.Join(data, separator), nil
+	}, nil
+}
+
+// newAliasedSliceFormatter returns the formatter of a byte or rune slice holding a single value
+func newAliasedSliceFormatter(aliasType string) formatter {
+	if aliasType == constAliasTypeByte {
+		return func(field reflect.Value) (string, error) {
+			return string(field.Bytes()), nil
+		}
+	}
+	return func(field reflect.Value) (string, error) {
+		var b strings.Builder
+		for idx := 0; idx < field.Len(); idx++ {
+			next := rune(-1)
+			if idx+1 < field.Len() {
+				next = rune(field.Index(idx + 1).Int())
+			}
+			b.WriteString(quoteRune(rune(field.Index(idx).Int()), next))
+		}
+		return b.String(), nil
+	}
+}
+
+// formatJSON encodes the value of field as JSON
+func formatJSON(field reflect.Value) (string, error) {
+	data, err := json.Marshal(field.Interface())
+	return string(data), err
+}
+
+// newScalarFormatter returns the formatter for t, the inverse of newScalarParser
+func newScalarFormatter(t reflect.Type, aliasType string) (formatter, error) {
+	switch {
+	case t.String() == "time.Duration":
+		return func(field reflect.Value) (string, error) {
+			return time.Duration(field.Int()).String(), nil
+		}, nil
+
+	case t.String() == "time.Time":
+		return func(field reflect.Value) (string, error) {
+			return field.Interface().(time.Time).Format(time.RFC3339Nano), nil
+		}, nil
+
+	case t.String() == "*regexp.Regexp":
+		return func(field reflect.Value) (string, error) {
+			if field.IsNil() {
+				return "", nil
+			}
+			return field.Interface().(*regexp.Regexp).String(), nil
+		}, nil
+
+	case t == typeFileMode:
+		return func(field reflect.Value) (string, error) {
+			return formatFileMode(os.FileMode(field.Uint()))
+		}, nil
+
+	case t.Kind() == reflect.Uint8 && aliasType == constAliasTypeByte:
+		return func(field reflect.Value) (string, error) {
+			if b := byte(field.Uint()); b >= 0x20 && b < 0x7f {
+				return string(rune(b)), nil
+			}
+			return fmt.Sprintf("\\x%02x", field.Uint()), nil
+		}, nil
+
+	case t.Kind() == reflect.Int32 && aliasType == constAliasTypeRune:
+		return func(field reflect.Value) (string, error) {
+			return quoteRune(rune(field.Int()), -1), nil
+		}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return func(field reflect.Value) (string, error) {
+			return field.String(), nil
+		}, nil
+
+	case reflect.Bool:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatBool(field.Bool()), nil
+		}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatUint(field.Uint(), 10), nil
+		}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatInt(field.Int(), 10), nil
+		}, nil
+
+	case reflect.Float32, reflect.Float64:
+		bitSize := t.Bits()
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatFloat(field.Float(), 'g', -1, bitSize), nil
+		}, nil
+	}
+
+	return nil, errUnrecognizedType
+}
+
+// formatFileMode formats the permission bits of mode in octal notation, including the
+// setuid, setgid and sticky bits
+func formatFileMode(mode os.FileMode) (string, error) {
+	if mode&^(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) != 0 {
+		return "", fmt.Errorf("file mode %s has bits other than permissions", mode)
+	}
+	perm := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return fmt.Sprintf("%04o", perm), nil
+}
+
+// quoteRune encodes r in the form read by unquoteRune, given the rune following it or -1.
+// Printable characters are kept as is, others are written as escape sequences.
+func quoteRune(r, next rune) string {
+	switch {
+	case r == '\\':
+		return `\\`
+	case r == 'U' && next == '+':
+		return `\x55`
+	case unicode.IsPrint(r):
+		return string(r)
+	case !utf8.ValidRune(r):
+		return string(utf8.RuneError)
+	}
+	quoted := strconv.QuoteRune(r)
+	return quoted[1 : len(quoted)-1]
+}
+
+// escapingFormatter wraps format to escape its values with the quoted option, or to fail for
+// values which can't be represented without it. Separators are those of all slice levels.
+func escapingFormatter(format formatter, separators []string, opts valueOptions) formatter {
+	return func(field reflect.Value) (string, error) {
+		value, err := format(field)
+		if err != nil {
+			return "", err
+		}
+		return escapeValue(value, separators, opts.quoted, opts.trim)
+	}
+}
+
+// escapeValue is the inverse of splitValue for a single element. With quoted, quotes,
+// backslashes and the characters of separators are escaped by a backslash, as is whitespace
+// around the value if trim would remove it.
+func escapeValue(value string, separators []string, quoted, trim bool) (string, error) {
+	leading := len(value) - len(strings.TrimLeftFunc(value, unicode.IsSpace))
+	trailing := len(strings.TrimRightFunc(value, unicode.IsSpace))
+
+	if !quoted {
+		for _, separator := range separators {
+			if strings.Contains(value, separator) {
+				return "", fmt.Errorf("value \"%s\" contains the separator \"%s\", which requires the quoted option", value, separator)
+			}
+		}
+		if trim && (leading > 0 || trailing < len(value)) {
+			return "", fmt.Errorf("value \"%s\" has whitespace removed by the trim option", value)
+		}
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		escape := c == '\\' || c == '"' || trim && (i < leading || i >= trailing)
+		for _, separator := range separators {
+			escape = escape || strings.IndexByte(separator, c) >= 0
+		}
+		if escape {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	constGroupExclusive  = "exclusive"
+	constGroupAtLeastOne = "atleastone"
+)
+
+// group is a set of variables of which at most one, or at least one, must be set
+type group struct {
+	name       string
+	names      []string
+	exclusive  bool
+	atLeastOne bool
+}
+
+// addGroup adds the variable name to the group groupName of groups, combining the rules
+// declared by its members. The groups are copied on change as they may belong to the
+// cached plan of a nested struct.
+func addGroup(groups []group, groupName, name string, exclusive, atLeastOne bool) []group {
+	for idx := range groups {
+		if groups[idx].name == groupName {
+			g := groups[idx]
+			g.names = append(g.names[:len(g.names):len(g.names)], name)
+			g.exclusive, g.atLeastOne = g.exclusive || exclusive, g.atLeastOne || atLeastOne
+			groups[idx] = g
+			return groups
+		}
+	}
+	return append(groups, group{name: groupName, names: []string{name}, exclusive: exclusive, atLeastOne: atLeastOne})
+}
+
+// mergeGroups adds the groups of a nested struct to groups
+func mergeGroups(groups, nested []group) []group {
+	for _, g := range nested {
+		for _, name := range g.names {
+			groups = addGroup(groups, g.name, name, g.exclusive, g.atLeastOne)
+		}
+	}
+	return groups
+}
+
+// checkGroups returns an error for every group of which more than one variable is set while
+// being exclusive, or none while requiring at least one. Variables are resolved like the
+// conditions of required_if, falling back to the default of their field.
+func checkGroups(groups []group, vars map[string]*fieldPlan) Errors {
+	var errs Errors
+	for _, g := range groups {
+		var set []string
+		for _, name := range g.names {
+			if _, ok := resolve(name, vars); ok {
+				set = append(set, name)
+			}
+		}
+
+		switch {
+		case g.exclusive && len(set) > 1:
+			errs = append(errs, &ParseError{
+				Option: constGroupExclusive,
+				Err:    fmt.Errorf("group %s: only one of %s may be set, but %s are", g.name, strings.Join(g.names, ", "), strings.Join(set, ", ")),
+				kind:   ErrInvalidValue,
+			})
+		case g.atLeastOne && len(set) == 0:
+			errs = append(errs, &ParseError{
+				Option: constGroupAtLeastOne,
+				Err:    fmt.Errorf("group %s: one of %s is required", g.name, strings.Join(g.names, ", ")),
+				kind:   ErrRequired,
+			})
+		}
+	}
+	return errs
+}
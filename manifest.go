@@ -0,0 +1,93 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteKubernetes writes the env list of a Kubernetes container spec for the variables read
+// by the struct v points to, including nested structs. Variables of fields with a non-zero
+// value are set to it and required ones to an empty value to fill in, others are commented
+// out, showing their default if they have one. Secret fields are read from the key of their
+// variable in the Kubernetes secret secretName.
+//
+// Example usage:
+//
+//	env.WriteKubernetes(&Config{Host: "0.0.0.0"}, "app-secrets", os.Stdout)
+//
+//	env:
+//	  # address to listen on
+//	  - name: HOST
+//	    value: "0.0.0.0"
+//	  - name: DB_PASSWORD
+//	    valueFrom:
+//	      secretKeyRef:
+//	        name: app-secrets
+//	        key: DB_PASSWORD
+func WriteKubernetes(v interface{}, secretName string, w io.Writer) error {
+	return writeManifest(v, w, "env:\n", func(bw *bufio.Writer, f *fieldPlan, value string) {
+		switch {
+		case f.secret:
+			fmt.Fprintf(bw, "  - name: %s\n    valueFrom:\n      secretKeyRef:\n        name: %s\n        key: %s\n", f.name, secretName, f.name)
+			if !f.required {
+				bw.WriteString("        optional: true\n")
+			}
+		case value != "" || f.required:
+			fmt.Fprintf(bw, "  - name: %s\n    value: %s\n", f.name, strconv.Quote(value))
+		default:
+			fmt.Fprintf(bw, "  # - name: %s\n  #   value: %s\n", f.name, strconv.Quote(f.appliedDefault()))
+		}
+	})
+}
+
+// WriteCompose writes the environment block of a docker-compose service for the variables
+// read by the struct v points to, including nested structs. Values are written like those of
+// WriteKubernetes, but secret fields are interpolated from the variable of the same name in
+// the environment of docker-compose, failing if it is unset for required fields.
+//
+// Example usage:
+//
+//	env.WriteCompose(&Config{Host: "0.0.0.0"}, os.Stdout)
+//
+//	environment:
+//	  # address to listen on
+//	  HOST: "0.0.0.0"
+//	  DB_PASSWORD: ${DB_PASSWORD:?DB_PASSWORD is required}
+func WriteCompose(v interface{}, w io.Writer) error {
+	return writeManifest(v, w, "environment:\n", func(bw *bufio.Writer, f *fieldPlan, value string) {
+		switch {
+		case f.secret && f.required:
+			fmt.Fprintf(bw, "  %s: ${%s:?%s is required}\n", f.name, f.name, f.name)
+		case f.secret:
+			fmt.Fprintf(bw, "  %s: ${%s}\n", f.name, f.name)
+		case value != "" || f.required:
+			fmt.Fprintf(bw, "  %s: %s\n", f.name, quoteCompose(value))
+		default:
+			fmt.Fprintf(bw, "  # %s: %s\n", f.name, quoteCompose(f.appliedDefault()))
+		}
+	})
+}
+
+// writeManifest writes header followed by an entry for every variable read by the struct v
+// points to, preceded by its description, with the value of walkFormatted
+func writeManifest(v interface{}, w io.Writer, header string, entry func(bw *bufio.Writer, f *fieldPlan, value string)) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(header)
+	err := walkFormatted(v, func(f *fieldPlan, value string) {
+		writeComment(bw, "  ", f.desc)
+		entry(bw, f, value)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// quoteCompose double quotes value as a YAML string, escaping the $ of docker-compose
+// interpolation
+func quoteCompose(value string) string {
+	return strconv.Quote(strings.ReplaceAll(value, "$", "$$"))
+}
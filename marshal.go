@@ -0,0 +1,62 @@
+package env
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Marshal is the inverse of Parse, returning the environment variables which parse to the
+// current values of the struct v points to. It uses the same tags, so separators, type=byte
+// and type=rune aliases and quoting apply. Zero values are left out unless their field has a
+// default, which would otherwise replace them when parsed.
+//
+// An empty string can only be restored over a default with allowempty. Values which can't be
+// represented, such as slice elements containing the separator without the quoted option,
+// are returned as Errors.
+func Marshal(v interface{}) (map[string]string, error) {
+	vars, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// Environ returns the variables of Marshal as "NAME=value" entries sorted by name, as used
+// by os.Environ and exec.Cmd.Env. Variables which can't be marshalled are left out, use
+// Marshal to detect them.
+func Environ(v interface{}) []string {
+	vars, _ := marshal(v)
+	environ := make([]string, 0, len(vars))
+	for name, value := range vars {
+		environ = append(environ, name+"="+value)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// marshal returns the variables of all fields of v which could be formatted, along with the
+// errors of those which could not
+func marshal(v interface{}) (map[string]string, error) {
+	s, plan, err := structPlanOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{}
+	var errs Errors
+	plan.walk(s, "", func(f *fieldPlan, field reflect.Value, path string) {
+		if field.IsZero() && f.defaultValue == "" {
+			return
+		}
+		value, err := f.format(field)
+		if err != nil {
+			errs = append(errs, &ParseError{Var: f.name, FieldPath: path, Err: err, kind: ErrInvalidValue})
+			return
+		}
+		vars[f.name] = value
+	})
+	if len(errs) > 0 {
+		return vars, errs
+	}
+	return vars, nil
+}
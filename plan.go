@@ -0,0 +1,212 @@
+package env
+
+import (
+	"reflect"
+	"sync"
+)
+
+// structPlan is the compiled form of the env tags of a struct type. It is built once per
+// type and cached, so repeated calls to Parse don't have to inspect the tags again.
+type structPlan struct {
+	fields   []fieldPlan
+	validate bool                  // the struct implements validator
+	vars     map[string]*fieldPlan // fields by variable name, including nested structs
+	groups   []group               // groups of variables, including nested structs
+}
+
+// fieldPlan holds the parsed tag options and the value parser of a single field
+type fieldPlan struct {
+	index     int
+	nested    *structPlan
+	fieldName string // name of the struct field, used in the path of its errors
+
+	typ           reflect.Type
+	valueType     reflect.Type // the type values are parsed to, which is held by typ for Secret fields
+	desc          string       // the desc tag of the field
+	name          string
+	defaultValue  string
+	required      bool
+	requiredFirst bool // required listed before default= is checked against the environment only
+	secret        bool // the value is sensitive and kept out of errors and templates
+	holder        bool // the field is a Secret holding the value
+	allowEmpty    bool // set but empty variables count as set
+	notEmpty      bool // set but empty variables are an error
+	conditions    []Condition
+	group         string
+	exclusive     bool
+	atLeastOne    bool
+	allowedValues bool
+	opts          valueOptions
+	rules         validation
+	parse         parser
+	format        formatter
+	check         check
+}
+
+type cachedPlan struct {
+	plan *structPlan
+	errs Errors
+}
+
+var (
+	plansMu sync.RWMutex
+	plans   = map[reflect.Type]cachedPlan{}
+)
+
+// planFor returns the cached plan for the struct type t, compiling it on first use.
+// Errors in the tags of t or any nested struct are returned as Errors.
+func planFor(t reflect.Type) (*structPlan, error) {
+	plan, errs := cachedPlanFor(t)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return plan, nil
+}
+
+func cachedPlanFor(t reflect.Type) (*structPlan, Errors) {
+	plansMu.RLock()
+	cached, ok := plans[t]
+	plansMu.RUnlock()
+	if ok {
+		return cached.plan, cached.errs
+	}
+
+	// compile outside of the lock as nested structs are planned recursively
+	plan, errs := compileStruct(t)
+	plansMu.Lock()
+	plans[t] = cachedPlan{plan: plan, errs: errs}
+	plansMu.Unlock()
+	return plan, errs
+}
+
+func compileStruct(t reflect.Type) (*structPlan, Errors) {
+	plan := &structPlan{validate: reflect.PtrTo(t).Implements(validatorType), vars: map[string]*fieldPlan{}}
+	var errs Errors
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		tagValue := fieldType.Tag.Get("env")
+
+		if tagValue == "" {
+			// nested structs without a tag of their own are parsed recursively
+			if fieldType.Type.Kind() == reflect.Struct {
+				nested, nestedErrs := cachedPlanFor(fieldType.Type)
+				errs = append(errs, nestedErrs.prefixed(fieldType.Name)...)
+				if nested != nil {
+					plan.groups = mergeGroups(plan.groups, nested.groups)
+				}
+				plan.fields = append(plan.fields, fieldPlan{index: i, nested: nested, fieldName: fieldType.Name})
+			}
+			continue
+		}
+
+		field, err := compileField(fieldType.Type, tagValue)
+		if err != nil {
+			err.FieldPath = fieldType.Name
+			errs = append(errs, err)
+			continue
+		}
+		field.index, field.fieldName, field.desc = i, fieldType.Name, fieldType.Tag.Get("desc")
+		plan.fields = append(plan.fields, field)
+		if field.group != "" {
+			plan.groups = addGroup(plan.groups, field.group, field.name, field.exclusive, field.atLeastOne)
+		}
+	}
+
+	// fields don't move anymore
+	for i := range plan.fields {
+		f := &plan.fields[i]
+		if f.nested == nil {
+			plan.vars[f.name] = f
+			continue
+		}
+		for name, nestedField := range f.nested.vars {
+			plan.vars[name] = nestedField
+		}
+	}
+	return plan, errs
+}
+
+// walk calls fn for every field of p and its nested structs in declaration order, along with
+// the field of s, a value of the planned struct type, and the path of the field
+func (p *structPlan) walk(s reflect.Value, path string, fn func(f *fieldPlan, field reflect.Value, path string)) {
+	for i := range p.fields {
+		f := &p.fields[i]
+		fieldPath := f.fieldName
+		if path != "" {
+			fieldPath = path + "." + f.fieldName
+		}
+		if f.nested != nil {
+			f.nested.walk(s.Field(f.index), fieldPath, fn)
+		} else {
+			fn(f, s.Field(f.index), fieldPath)
+		}
+	}
+}
+
+// appliedDefault returns the default of f, or an empty string if it doesn't apply as required
+// is listed before default=
+func (f *fieldPlan) appliedDefault() string {
+	if f.requiredFirst {
+		return ""
+	}
+	return f.defaultValue
+}
+
+func compileField(t reflect.Type, tagValue string) (fieldPlan, *ParseError) {
+	tag, tagErr := parseTag(tagValue)
+	if tagErr != nil {
+		return fieldPlan{}, tagErr
+	}
+
+	f := fieldPlan{
+		typ:           t,
+		name:          tag.Name,
+		defaultValue:  tag.Default,
+		required:      tag.Required,
+		requiredFirst: tag.requiredFirst,
+		conditions:    tag.Conditions,
+		secret:        tag.Secret,
+		allowEmpty:    tag.AllowEmpty,
+		notEmpty:      tag.NotEmpty,
+		group:         tag.Group,
+		exclusive:     tag.Exclusive,
+		atLeastOne:    tag.AtLeastOne,
+		rules:         tag.rules,
+		opts: valueOptions{
+			separators: tag.Separators,
+			aliasType:  tag.Type,
+			format:     tag.Format,
+			oneOf:      tag.OneOf,
+			quoted:     tag.Quoted,
+			trim:       tag.Trim,
+		},
+	}
+
+	// Secret fields are parsed like the value they hold
+	if inner, ok := secretType(t); ok {
+		t, f.secret, f.holder = inner, true, true
+	}
+	f.valueType = t
+
+	var err error
+	if f.parse, err = newParser(t, f.opts); err != nil {
+		if err == errUnrecognizedType || err == errUnrecognizedSliceType {
+			return fieldPlan{}, &ParseError{Var: f.name, Err: err, kind: ErrUnsupportedType}
+		}
+		return fieldPlan{}, &ParseError{Var: f.name, Err: err, kind: ErrInvalidTag}
+	}
+	if f.format, err = newFormatter(t, f.opts); err != nil {
+		return fieldPlan{}, &ParseError{Var: f.name, Err: err, kind: ErrUnsupportedType}
+	}
+	if f.holder {
+		format := f.format
+		f.format = func(field reflect.Value) (string, error) {
+			return format(secretValueOf(field))
+		}
+	}
+	f.allowedValues = f.opts.format != constFormatJSON && hasAllowedValues(t)
+	if f.check, err = newCheck(t, f.rules, f.opts.aliasType); err != nil {
+		return fieldPlan{}, &ParseError{Var: f.name, Err: err, kind: ErrInvalidTag}
+	}
+	return f, nil
+}
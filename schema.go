@@ -0,0 +1,252 @@
+package env
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Patterns describing the notations accepted for durations and file modes
+const (
+	durationPattern = `^[-+]?(0|([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+$`
+	fileModePattern = `^(0[oO]?)?[0-7]{1,4}$|^-?[-r][-w][-xsS][-r][-w][-xsS][-r][-w][-xtT]$`
+)
+
+// JSONSchema returns a JSON Schema document describing the environment variables read by the
+// struct v points to as the properties of an object, including those of nested structs. Each
+// property has the JSON type of its field along with its format, allowed values, default,
+// constraints and description. Variables which are always required are listed as required,
+// those of required_with as dependentRequired. Other conditions and groups aren't described.
+//
+// Durations, times, regular expressions, file modes and byte and rune aliases are described
+// as strings in the notation Parse accepts, slices as arrays and format=json fields by any
+// JSON value.
+func JSONSchema(v interface{}) ([]byte, error) {
+	s, plan, err := structPlanOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	dependentRequired := map[string][]string{}
+	var errs Errors
+	plan.walk(s, "", func(f *fieldPlan, _ reflect.Value, path string) {
+		property, err := f.schema()
+		if err != nil {
+			errs = append(errs, &ParseError{Var: f.name, FieldPath: path, Value: redact(f.defaultValue), Option: "default", Err: err, kind: ErrInvalidTag})
+			return
+		}
+		properties[f.name] = property
+
+		if f.required {
+			required = append(required, f.name)
+		}
+		for _, c := range f.conditions {
+			if c.Option == constRequiredWith {
+				dependentRequired[c.Var] = append(dependentRequired[c.Var], f.name)
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	schema := map[string]interface{}{
+		"$schema":    jsonSchemaDraft,
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if len(dependentRequired) > 0 {
+		schema["dependentRequired"] = dependentRequired
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schema returns the JSON Schema of the variable of f
+func (f *fieldPlan) schema() (map[string]interface{}, error) {
+	property := map[string]interface{}{}
+	if f.opts.format != constFormatJSON {
+		property = typeSchema(f.valueType, f.opts.aliasType)
+		f.addConstraints(property)
+		if err := f.addEnum(property); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.desc != "" {
+		property["description"] = f.desc
+	}
+	if f.secret {
+		property["writeOnly"] = true
+	}
+	if defaultValue := f.appliedDefault(); defaultValue != "" {
+		if f.opts.format == constFormatJSON {
+			property["default"] = json.RawMessage(defaultValue)
+		} else {
+			value := reflect.New(f.valueType).Elem()
+			if err := f.parse(value, defaultValue); err != nil {
+				return nil, err
+			}
+			property["default"] = schemaValue(value, f.opts.aliasType)
+		}
+	}
+	return property, nil
+}
+
+// leafSchema returns the schema of the innermost values of a slice property, or property itself
+func leafSchema(property map[string]interface{}) map[string]interface{} {
+	for property["type"] == "array" {
+		property = property["items"].(map[string]interface{})
+	}
+	return property
+}
+
+// addConstraints adds the validation options of f to property, applying to the innermost
+// values of slices except for minitems and maxitems
+func (f *fieldPlan) addConstraints(property map[string]interface{}) {
+	if f.rules.hasMinItems {
+		property["minItems"] = f.rules.minItems
+	}
+	if f.rules.hasMaxItems {
+		property["maxItems"] = f.rules.maxItems
+	}
+
+	leaf := leafSchema(property)
+	leafType := f.valueType
+	for leafType.Kind() == reflect.Slice && !isAliasedSlice(leafType, f.opts.aliasType) {
+		leafType = leafType.Elem()
+	}
+
+	// bounds of durations, file modes and aliases, described as strings, can't be compared
+	var minKey, maxKey string
+	switch {
+	case leafType.Kind() == reflect.String || isAliasedSlice(leafType, f.opts.aliasType):
+		minKey, maxKey = "minLength", "maxLength"
+	case leaf["type"] == "integer" || leaf["type"] == "number":
+		minKey, maxKey = "minimum", "maximum"
+	}
+	if minKey != "" && f.rules.min != "" {
+		leaf[minKey] = schemaNumber(f.rules.min)
+	}
+	if maxKey != "" && f.rules.max != "" {
+		leaf[maxKey] = schemaNumber(f.rules.max)
+	}
+	if f.rules.length != "" {
+		leaf["minLength"], leaf["maxLength"] = schemaNumber(f.rules.length), schemaNumber(f.rules.length)
+	}
+	if f.rules.pattern != nil {
+		leaf["pattern"] = f.rules.pattern.String()
+	}
+}
+
+// schemaNumber converts a bound, which has been validated when compiling the plan, to a number
+func schemaNumber(bound string) json.Number {
+	n, _ := strconv.ParseFloat(bound, 64)
+	return json.Number(strconv.FormatFloat(n, 'g', -1, 64))
+}
+
+// addEnum adds the values of the oneof option, or those of a Values method, to the innermost
+// values of property
+func (f *fieldPlan) addEnum(property map[string]interface{}) error {
+	t := f.valueType
+	for t.Kind() == reflect.Slice && !isAliasedSlice(t, f.opts.aliasType) {
+		t = t.Elem()
+	}
+
+	var enum []interface{}
+	if allowed, ok := allowedValues(t); ok {
+		for i := 0; i < allowed.Len(); i++ {
+			enum = append(enum, schemaValue(allowed.Index(i), f.opts.aliasType))
+		}
+	} else if f.opts.oneOf != nil {
+		parse, err := newScalarParser(t, f.opts.aliasType)
+		if err != nil {
+			return err
+		}
+		for _, option := range f.opts.oneOf {
+			value := reflect.New(t).Elem()
+			if err := parse(value, option); err != nil {
+				return err
+			}
+			enum = append(enum, schemaValue(value, f.opts.aliasType))
+		}
+	}
+	if enum != nil {
+		leafSchema(property)["enum"] = enum
+	}
+	return nil
+}
+
+// typeSchema returns the schema describing values of type t
+func typeSchema(t reflect.Type, aliasType string) map[string]interface{} {
+	switch {
+	case t.String() == "time.Duration":
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	case t.String() == "time.Time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.String() == "*regexp.Regexp":
+		return map[string]interface{}{"type": "string", "format": "regex"}
+	case t == typeFileMode:
+		return map[string]interface{}{"type": "string", "pattern": fileModePattern}
+	case isAliasedSlice(t, aliasType),
+		t.Kind() == reflect.Uint8 && aliasType == constAliasTypeByte,
+		t.Kind() == reflect.Int32 && aliasType == constAliasTypeRune:
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), aliasType)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// schemaValue converts v to the JSON value described by typeSchema, such as a default
+func schemaValue(v reflect.Value, aliasType string) interface{} {
+	t := v.Type()
+	if t.Kind() == reflect.Slice && !isAliasedSlice(t, aliasType) {
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = schemaValue(v.Index(i), aliasType)
+		}
+		return values
+	}
+	if typeSchema(t, aliasType)["type"] == "string" && t.Kind() != reflect.String {
+		format, _ := newScalarFormatter(t, aliasType)
+		if isAliasedSlice(t, aliasType) {
+			format = newAliasedSliceFormatter(aliasType)
+		}
+		value, _ := format(v)
+		return value
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return json.Number(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return json.Number(strconv.FormatFloat(v.Float(), 'g', -1, t.Bits()))
+	}
+	return v.Interface()
+}
@@ -0,0 +1,105 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+)
+
+// Secret holds a value parsed like a field of type T which is redacted whenever it is
+// printed, encoded as JSON or logged with log/slog. Fields of type Secret are treated as if
+// tagged with the secret option.
+//
+// Example usage:
+//
+//	type Config struct {
+//		Password env.Secret[string] `env:"DB_PASSWORD,required"`
+//	}
+//
+//	fmt.Printf("%+v\n", cfg)          // {Password:[REDACTED]}
+//	db.Connect(cfg.Password.Value())
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret returns a Secret holding value
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Value returns the secret value
+func (s Secret[T]) Value() T {
+	return s.value
+}
+
+// String returns a redacted placeholder instead of the value
+func (s Secret[T]) String() string {
+	return redactedValue
+}
+
+// GoString returns a redacted placeholder instead of the value for the %#v verb
+func (s Secret[T]) GoString() string {
+	return redactedValue
+}
+
+// Format writes a redacted placeholder instead of the value for every verb, so flags such
+// as %+v or %x don't print the value either
+func (s Secret[T]) Format(f fmt.State, verb rune) {
+	io.WriteString(f, redactedValue)
+}
+
+// MarshalJSON encodes a redacted placeholder instead of the value
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redactedValue)
+}
+
+// LogValue implements slog.LogValuer, logging a redacted placeholder instead of the value
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(redactedValue)
+}
+
+// secretValue returns the settable value held by s
+func (s *Secret[T]) secretValue() reflect.Value {
+	return reflect.ValueOf(&s.value).Elem()
+}
+
+// secretHolder is implemented by pointers to Secret, giving access to the value for parsing
+type secretHolder interface {
+	secretValue() reflect.Value
+}
+
+var secretHolderType = reflect.TypeOf((*secretHolder)(nil)).Elem()
+
+// secretType returns the type of the value held by t if it is a Secret
+func secretType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || !reflect.PtrTo(t).Implements(secretHolderType) {
+		return nil, false
+	}
+	return t.Field(0).Type, true
+}
+
+// secretValueOf returns the settable value held by field, an addressable Secret
+func secretValueOf(field reflect.Value) reflect.Value {
+	return field.Addr().Interface().(secretHolder).secretValue()
+}
+
+// RedactError hides the message of err, which may contain a secret value, keeping err for
+// errors.Is and errors.As. Parse applies it to the errors of secret fields.
+func RedactError(err error) error {
+	return &secretError{err: err}
+}
+
+// secretError hides the message of an error of a secret field, as it may contain the value
+type secretError struct {
+	err error
+}
+
+func (e *secretError) Error() string {
+	return ErrInvalidValue.Error()
+}
+
+func (e *secretError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,117 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownVariable is reported by ParseStrict for variables with the prefix which no field reads
+var ErrUnknownVariable = errors.New("unknown variable")
+
+// ParseStrict parses the environment like Parse and additionally reports every environment
+// variable starting with prefix which is not read by any field of v, along with the closest
+// known variable name as a suggestion for typos.
+//
+// A typo in MYAPP_DB_HOST is reported as:
+//
+//	MYAPP_DB_HSOT: unknown variable, did you mean MYAPP_DB_HOST?
+func ParseStrict(v interface{}, prefix string) error {
+	err := Parse(v)
+
+	var errs Errors
+	if err != nil && !errors.As(err, &errs) {
+		return err
+	}
+
+	// errors in the tags of v are returned without checking the environment
+	plan, planErr := planFor(reflect.ValueOf(v).Elem().Type())
+	if planErr != nil {
+		return planErr
+	}
+	known := map[string]bool{}
+	plan.collectNames(known)
+
+	var unknown []string
+	for _, entry := range os.Environ() {
+		name := strings.SplitN(entry, "=", 2)[0]
+		if strings.HasPrefix(name, prefix) && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		msg := ErrUnknownVariable.Error()
+		if suggestion := closestName(name, known); suggestion != "" {
+			msg = fmt.Sprintf("%s, did you mean %s?", msg, suggestion)
+		}
+		errs = append(errs, &ParseError{Var: name, Value: redact(os.Getenv(name)), Err: errors.New(msg), kind: ErrUnknownVariable})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// collectNames adds the variable names of all fields of p, including nested structs, to names,
+// along with the variables their conditions depend on
+func (p *structPlan) collectNames(names map[string]bool) {
+	for i := range p.fields {
+		if p.fields[i].nested != nil {
+			p.fields[i].nested.collectNames(names)
+			continue
+		}
+		names[p.fields[i].name] = true
+		for _, c := range p.fields[i].conditions {
+			names[c.Var] = true
+		}
+	}
+}
+
+// closestName returns the known name with the smallest edit distance to name, or an empty
+// string if none is close enough to be a likely typo
+func closestName(name string, known map[string]bool) string {
+	best, bestDistance := "", len(name)/3+1
+	for candidate := range known {
+		distance := levenshtein(name, candidate)
+		if distance < bestDistance || distance == bestDistance && best != "" && candidate < best {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the number of single character insertions, deletions and substitutions
+// needed to turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
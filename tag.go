@@ -0,0 +1,193 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Tag is the parsed form of an env struct tag, giving tools such as documentation generators
+// and linters the options Parse reads from it
+type Tag struct {
+	Name       string
+	Default    string
+	Required   bool
+	Conditions []Condition // required_if, required_unless and required_with options
+	Secret     bool
+	AllowEmpty bool
+	NotEmpty   bool
+	Type       string   // the alias type of type=, byte or rune
+	Format     string   // the format of format=, json
+	Separators []string // the separators of separator= or separators=, outermost level first
+	OneOf      []string
+	Quoted     bool
+	Trim       bool
+	Group      string
+	Exclusive  bool
+	AtLeastOne bool
+
+	// constraints as given in the tag, empty if not
+	Min, Max, Len, Pattern string
+	MinItems, MaxItems     string
+
+	requiredFirst bool // required listed before default= is checked against the environment only
+	rules         validation
+}
+
+// ParseTag parses the value of an env struct tag, validating its options independent of the
+// type of the field it belongs to. Errors are returned as a *ParseError.
+func ParseTag(tag string) (Tag, error) {
+	parsed, err := parseTag(tag)
+	if err != nil {
+		return parsed, err
+	}
+	return parsed, nil
+}
+
+// Requirement describes when the variable is required as listed by Usage, which is "yes",
+// conditions such as "if VAR=a|b" or an empty string if never
+func (t Tag) Requirement() string {
+	return describeRequirement(t.Required, t.Conditions)
+}
+
+// DefaultApplies reports whether unset variables are parsed from the default, which they
+// aren't for tags listing required before default=
+func (t Tag) DefaultApplies() bool {
+	return t.Default != "" && !t.requiredFirst
+}
+
+// CheckTag returns the error Parse reports for a field of type t with the env tag tag
+// regardless of the environment, which is an invalid tag, an unsupported type or a default
+// failing to parse or validate. Errors are returned as a *ParseError.
+func CheckTag(t reflect.Type, tag string) error {
+	f, err := compileField(t, tag)
+	if err != nil {
+		return err
+	}
+	if f.defaultValue == "" {
+		return nil
+	}
+
+	field := reflect.New(t).Elem()
+	if f.holder {
+		field = secretValueOf(field)
+	}
+	if err := f.setValue(field, f.defaultValue); err != nil {
+		if err.Option == "" {
+			err.Option = "default"
+		}
+		return err
+	}
+	return nil
+}
+
+func parseTag(tagValue string) (Tag, *ParseError) {
+	tags := splitTag(tagValue)
+	if len(tags) < 1 || len(tags[0]) == 0 {
+		return Tag{}, &ParseError{Err: errors.New("env variable name cannot be empty"), kind: ErrInvalidTag}
+	}
+
+	tag := Tag{Name: tags[0]}
+	tagError := func(option, format string, args ...interface{}) *ParseError {
+		return &ParseError{Var: tag.Name, Option: optionName(option), Err: fmt.Errorf(format, args...), kind: ErrInvalidTag}
+	}
+
+	for _, tagValue := range tags[1:] {
+		name := optionName(tagValue)
+		if tagValue == "required" {
+			if !tag.Required {
+				tag.Required, tag.requiredFirst = true, tag.Default == ""
+			}
+		} else if name == constRequiredIf || name == constRequiredUnless || name == constRequiredWith {
+			conditions, err := newConditions(name, namedOptionValue(tagValue))
+			if err != nil {
+				return Tag{}, tagError(tagValue, "%s", err)
+			}
+			tag.Conditions = append(tag.Conditions, conditions...)
+		} else if name == "default" {
+			if tag.Default == "" {
+				tag.Default = namedOptionValue(tagValue)
+			}
+		} else if name == "type" { // type allows override for go native aliases (byte,rune)
+			tag.Type = namedOptionValue(tagValue)
+
+			if tag.Type != constAliasTypeRune && tag.Type != constAliasTypeByte {
+				return Tag{}, tagError(tagValue, "invalid type \"%s\", valid options are: \"%s\", \"%s\"", tagValue, constAliasTypeByte, constAliasTypeRune)
+			}
+		} else if name == "oneof" {
+			tag.OneOf = strings.Split(namedOptionValue(tagValue), "|")
+		} else if name == "separators" {
+			tag.Separators = strings.Split(namedOptionValue(tagValue), "|")
+		} else if name == "separator" {
+			if tmp := namedOptionValue(tagValue); tmp != "" {
+				tag.Separators = []string{tmp}
+			}
+		} else if name == "format" {
+			tag.Format = namedOptionValue(tagValue)
+
+			if tag.Format != constFormatJSON {
+				return Tag{}, tagError(tagValue, "invalid format \"%s\", valid options are: \"%s\"", tagValue, constFormatJSON)
+			}
+		} else if name == "minitems" || name == "maxitems" {
+			n, err := strconv.Atoi(namedOptionValue(tagValue))
+			if err != nil || n < 0 {
+				return Tag{}, tagError(tagValue, "invalid item count \"%s\"", tagValue)
+			}
+			if name == "minitems" {
+				tag.MinItems, tag.rules.minItems, tag.rules.hasMinItems = namedOptionValue(tagValue), n, true
+			} else {
+				tag.MaxItems, tag.rules.maxItems, tag.rules.hasMaxItems = namedOptionValue(tagValue), n, true
+			}
+		} else if name == "min" {
+			tag.Min = namedOptionValue(tagValue)
+		} else if name == "max" {
+			tag.Max = namedOptionValue(tagValue)
+		} else if name == "len" {
+			tag.Len = namedOptionValue(tagValue)
+		} else if name == "pattern" {
+			pattern, err := regexp.Compile(namedOptionValue(tagValue))
+			if err != nil {
+				return Tag{}, tagError(tagValue, "invalid pattern \"%s\": %s", tagValue, err)
+			}
+			tag.Pattern, tag.rules.pattern = namedOptionValue(tagValue), pattern
+		} else if name == "group" {
+			tag.Group = namedOptionValue(tagValue)
+		} else if tagValue == constGroupExclusive {
+			tag.Exclusive = true
+		} else if tagValue == constGroupAtLeastOne {
+			tag.AtLeastOne = true
+		} else if tagValue == "secret" {
+			tag.Secret = true
+		} else if tagValue == "allowempty" {
+			tag.AllowEmpty = true
+		} else if tagValue == "notempty" {
+			tag.NotEmpty = true
+		} else if tagValue == "quoted" {
+			tag.Quoted = true
+		} else if tagValue == "trim" {
+			tag.Trim = true
+		} else {
+			return Tag{}, tagError(tagValue, "unknown option %s", tagValue)
+		}
+	}
+	tag.rules.min, tag.rules.max, tag.rules.length = tag.Min, tag.Max, tag.Len
+
+	if tag.AllowEmpty && tag.NotEmpty {
+		return Tag{}, tagError("", "allowempty and notempty cannot be combined")
+	}
+	if (tag.Exclusive || tag.AtLeastOne) && tag.Group == "" {
+		return Tag{}, tagError("", "%s and %s require a group= option", constGroupExclusive, constGroupAtLeastOne)
+	}
+	return tag, nil
+}
+
+// optionName returns the name of a tag option without its value
+func optionName(option string) string {
+	if idx := strings.Index(option, "="); idx >= 0 {
+		return option[:idx]
+	}
+	return option
+}
@@ -0,0 +1,115 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WriteTemplate writes a dotenv file listing every environment variable read by the struct v
+// points to, including nested structs, with its description, type, default and whether it is
+// required. Variables of fields with a non-zero value are filled in with it, unless secret,
+// others are left empty if required and commented out otherwise, showing their default if
+// they have one.
+//
+// Example usage:
+//
+//	env.WriteTemplate(&Config{}, file)
+//
+//	# address to listen on
+//	# type: string, default: localhost
+//	# HOST=localhost
+func WriteTemplate(v interface{}, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	first := true
+	err := walkFormatted(v, func(f *fieldPlan, value string) {
+		if !first {
+			bw.WriteString("\n")
+		}
+		first = false
+		writeComment(bw, "", f.desc)
+		writeComment(bw, "", f.templateInfo())
+
+		switch {
+		case value != "":
+			fmt.Fprintf(bw, "%s=%s\n", f.name, quoteDotenv(value))
+		case f.required:
+			fmt.Fprintf(bw, "%s=\n", f.name)
+		default:
+			fmt.Fprintf(bw, "# %s=%s\n", f.name, quoteDotenv(f.appliedDefault()))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// walkFormatted calls fn for every field of the struct v points to, including nested structs,
+// with its formatted value, which is left empty for zero and secret fields. Fields which can't
+// be formatted are skipped and returned as Errors.
+func walkFormatted(v interface{}, fn func(f *fieldPlan, value string)) error {
+	s, plan, err := structPlanOf(v)
+	if err != nil {
+		return err
+	}
+
+	var errs Errors
+	plan.walk(s, "", func(f *fieldPlan, field reflect.Value, path string) {
+		var value string
+		if !field.IsZero() && !f.secret {
+			var err error
+			if value, err = f.format(field); err != nil {
+				errs = append(errs, &ParseError{Var: f.name, FieldPath: path, Value: redact(value), Err: err, kind: ErrInvalidValue})
+				return
+			}
+		}
+		fn(f, value)
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// writeComment writes text as comments, prefixing each of its lines with indent and "# ".
+// Empty text writes nothing.
+func writeComment(w io.Writer, indent, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "%s# %s\n", indent, line)
+	}
+}
+
+// templateInfo describes the type, requirement and default of f on a single line
+func (f *fieldPlan) templateInfo() string {
+	info := []string{"type: " + f.typ.String()}
+	if requirement := f.requirement(); requirement == "yes" {
+		info = append(info, "required")
+	} else if requirement != "" {
+		info = append(info, "required "+requirement)
+	}
+	if defaultValue := f.appliedDefault(); defaultValue != "" {
+		info = append(info, "default: "+defaultValue)
+	}
+	if f.secret {
+		info = append(info, "secret")
+	}
+	if separators := f.separatorUsage(); separators != "" {
+		info = append(info, "separator: "+separators)
+	}
+	return strings.Join(info, ", ")
+}
+
+// quoteDotenv double quotes values which dotenv parsers would otherwise split or cut short
+func quoteDotenv(value string) string {
+	if strings.ContainsAny(value, " \t\r\n#\"'\\$") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,78 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Usage writes a table of the environment variables read by the struct v points to, including
+// nested structs, with their type, default, whether they are required, the separator of
+// slices and the description given in a desc tag.
+//
+// Example usage:
+//
+//	type Config struct {
+//		Host string `env:"HOST,default=localhost" desc:"address to listen on"`
+//	}
+//
+//	if *helpEnv {
+//		env.Usage(&Config{}, os.Stdout)
+//	}
+func Usage(v interface{}, w io.Writer) error {
+	s, plan, err := structPlanOf(v)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIABLE\tTYPE\tDEFAULT\tREQUIRED\tSEPARATOR\tDESCRIPTION")
+	plan.walk(s, "", func(f *fieldPlan, _ reflect.Value, _ string) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", f.name, f.typ, f.appliedDefault(), f.requirement(), f.separatorUsage(), f.desc)
+	})
+	return tw.Flush()
+}
+
+// requirement describes when the field is required, or returns an empty string if never
+func (f *fieldPlan) requirement() string {
+	return describeRequirement(f.required, f.conditions)
+}
+
+// describeRequirement returns "yes" if required, otherwise the conditions joined as in
+// "if VAR=a|b, unless VAR=c, with VAR"
+func describeRequirement(required bool, conditions []Condition) string {
+	if required {
+		return "yes"
+	}
+	descriptions := make([]string, len(conditions))
+	for idx, c := range conditions {
+		switch c.Option {
+		case constRequiredIf:
+			descriptions[idx] = fmt.Sprintf("if %s=%s", c.Var, strings.Join(c.Values, "|"))
+		case constRequiredUnless:
+			descriptions[idx] = fmt.Sprintf("unless %s=%s", c.Var, strings.Join(c.Values, "|"))
+		default:
+			descriptions[idx] = fmt.Sprintf("with %s", c.Var)
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// separatorUsage returns the separators of a slice field, outermost level first
+func (f *fieldPlan) separatorUsage() string {
+	if f.typ.Kind() != reflect.Slice || f.opts.format == constFormatJSON || isAliasedSlice(f.typ, f.opts.aliasType) {
+		return ""
+	}
+
+	var separators []string
+	for t, level := f.typ, 0; t.Kind() == reflect.Slice && !isAliasedSlice(t, f.opts.aliasType); t, level = t.Elem(), level+1 {
+		separator := DefaultSeparator
+		if level < len(f.opts.separators) && f.opts.separators[level] != "" {
+			separator = f.opts.separators[level]
+		}
+		separators = append(separators, separator)
+	}
+	return strings.Join(separators, " ")
+}
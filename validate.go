@@ -0,0 +1,222 @@
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// validator is implemented by structs validating themselves after their fields have been
+// parsed, e.g. to check fields depending on each other
+type validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*validator)(nil)).Elem()
+
+// validation holds the constraint options of a tag, checked after a value has been converted
+type validation struct {
+	min, max, length         string
+	pattern                  *regexp.Regexp
+	minItems, maxItems       int
+	hasMinItems, hasMaxItems bool
+}
+
+func (v validation) isEmpty() bool {
+	return v.min == "" && v.max == "" && v.length == "" && v.pattern == nil && !v.hasMinItems && !v.hasMaxItems
+}
+
+// check validates a converted value
+type check func(v reflect.Value) error
+
+// newCheck returns the check for values of type t. For slices min, max, len and pattern
+// apply to each element, minitems and maxitems to the number of elements. Byte and rune
+// slices aliased with type= are checked like strings.
+func newCheck(t reflect.Type, rules validation, aliasType string) (check, error) {
+	if rules.isEmpty() {
+		return nil, nil
+	}
+
+	if t.Kind() == reflect.Slice && !isAliasedSlice(t, aliasType) {
+		minItems, maxItems, hasMinItems, hasMaxItems := rules.minItems, rules.maxItems, rules.hasMinItems, rules.hasMaxItems
+		rules.hasMinItems, rules.hasMaxItems = false, false
+		elem, err := newCheck(t.Elem(), rules, aliasType)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) error {
+			if hasMinItems && v.Len() < minItems {
+				return optionError("minitems", fmt.Errorf("number of items must be at least %d but was %d", minItems, v.Len()))
+			}
+			if hasMaxItems && v.Len() > maxItems {
+				return optionError("maxitems", fmt.Errorf("number of items must be at most %d but was %d", maxItems, v.Len()))
+			}
+			if elem != nil {
+				for i := 0; i < v.Len(); i++ {
+					if err := elem(v.Index(i)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	if rules.hasMinItems || rules.hasMaxItems {
+		return nil, fmt.Errorf("minitems and maxitems are only supported for slices, not %s", t)
+	}
+	if t.Kind() == reflect.String || isAliasedSlice(t, aliasType) {
+		return newLengthCheck(rules)
+	}
+	if rules.length != "" || rules.pattern != nil {
+		return nil, fmt.Errorf("len and pattern are only supported for strings, not %s", t)
+	}
+	return newRangeCheck(t, rules)
+}
+
+// isAliasedSlice reports whether t is a byte or rune slice holding a single value
+func isAliasedSlice(t reflect.Type, aliasType string) bool {
+	return t.Kind() == reflect.Slice &&
+		(t.Elem().Kind() == reflect.Uint8 && aliasType == constAliasTypeByte ||
+			t.Elem().Kind() == reflect.Int32 && aliasType == constAliasTypeRune)
+}
+
+// newLengthCheck returns a check of the length and pattern of strings. The length of
+// strings and rune slices is counted in characters, that of byte slices in bytes.
+func newLengthCheck(rules validation) (check, error) {
+	var bounds [3]int
+	for idx, bound := range []string{rules.min, rules.max, rules.length} {
+		if bound == "" {
+			bounds[idx] = -1
+			continue
+		}
+		n, err := strconv.Atoi(bound)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid length \"%s\"", bound)
+		}
+		bounds[idx] = n
+	}
+	min, max, length := bounds[0], bounds[1], bounds[2]
+
+	return func(v reflect.Value) error {
+		var s string
+		var n int
+		switch v.Kind() {
+		case reflect.String:
+			s = v.String()
+			n = utf8.RuneCountInString(s)
+		case reflect.Slice:
+			n = v.Len()
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				s = string(v.Bytes())
+			} else {
+				s = string(v.Convert(reflect.TypeOf([]rune(nil))).Interface().([]rune))
+			}
+		}
+
+		switch {
+		case min >= 0 && n < min:
+			return optionError("min", fmt.Errorf("length must be at least %d but was %d", min, n))
+		case max >= 0 && n > max:
+			return optionError("max", fmt.Errorf("length must be at most %d but was %d", max, n))
+		case length >= 0 && n != length:
+			return optionError("len", fmt.Errorf("length must be %d but was %d", length, n))
+		case rules.pattern != nil && !rules.pattern.MatchString(s):
+			return optionError("pattern", fmt.Errorf("value must match pattern \"%s\"", rules.pattern))
+		}
+		return nil
+	}, nil
+}
+
+// bound is a min or max option parsed for the kind of value it is compared to
+type bound struct {
+	set bool
+	i   int64
+	u   uint64
+	f   float64
+}
+
+// newRangeCheck returns a check of the min and max bounds of numbers and durations
+func newRangeCheck(t reflect.Type, rules validation) (check, error) {
+	isDuration := t.String() == "time.Duration"
+	kind := t.Kind()
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return nil, fmt.Errorf("min and max are not supported for %s", t)
+	}
+
+	parseBound := func(value string) (b bound, err error) {
+		if value == "" {
+			return b, nil
+		}
+		b.set = true
+		switch {
+		case isDuration:
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			b.i = int64(d)
+		case kind >= reflect.Int && kind <= reflect.Int64:
+			b.i, err = strconv.ParseInt(value, 10, 64)
+		case kind >= reflect.Uint && kind <= reflect.Uint64:
+			b.u, err = strconv.ParseUint(value, 10, 64)
+		default:
+			b.f, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil {
+			return b, fmt.Errorf("invalid bound \"%s\" for %s", value, t)
+		}
+		return b, nil
+	}
+	min, err := parseBound(rules.min)
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseBound(rules.max)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare returns -1, 0 or 1 as v is less than, equal to or greater than b
+	compare := func(v reflect.Value, b bound) int {
+		switch {
+		case kind >= reflect.Int && kind <= reflect.Int64:
+			return compareOrdered(v.Int() < b.i, v.Int() > b.i)
+		case kind >= reflect.Uint && kind <= reflect.Uint64:
+			return compareOrdered(v.Uint() < b.u, v.Uint() > b.u)
+		default:
+			return compareOrdered(v.Float() < b.f, v.Float() > b.f)
+		}
+	}
+	format := func(v reflect.Value) string {
+		if isDuration {
+			return time.Duration(v.Int()).String()
+		}
+		return fmt.Sprint(v.Interface())
+	}
+
+	return func(v reflect.Value) error {
+		if min.set && compare(v, min) < 0 {
+			return optionError("min", fmt.Errorf("value must be at least %s but was %s", rules.min, format(v)))
+		}
+		if max.set && compare(v, max) > 0 {
+			return optionError("max", fmt.Errorf("value must be at most %s but was %s", rules.max, format(v)))
+		}
+		return nil
+	}, nil
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}